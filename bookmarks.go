@@ -0,0 +1,261 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/log"
+	"github.com/limetext/backend/render"
+	"github.com/limetext/text"
+)
+
+const (
+	// The key of the regions marking the bookmarks.
+	bookmarksKey = "bookmarks"
+	// The extension of the file next to the project file
+	// the bookmarks are persisted in.
+	bookmarksExt = ".lime-bookmarks"
+)
+
+type (
+	// ToggleBookmark command bookmarks the selections, or removes
+	// the bookmarks on their lines if there are any.
+	ToggleBookmark struct {
+		backend.BypassUndoCommand
+	}
+
+	// NextBookmark command selects the bookmark after the
+	// selections, wrapping around to the first one.
+	NextBookmark struct {
+		backend.BypassUndoCommand
+	}
+
+	// PrevBookmark command selects the bookmark before the
+	// selections, wrapping around to the last one.
+	PrevBookmark struct {
+		backend.BypassUndoCommand
+	}
+
+	// SelectAllBookmarks command selects all the bookmarks.
+	SelectAllBookmarks struct {
+		backend.BypassUndoCommand
+	}
+
+	// ClearBookmarks command removes all the bookmarks.
+	ClearBookmarks struct {
+		backend.BypassUndoCommand
+	}
+)
+
+// setBookmarks replaces the bookmarks of v with rs, persisting
+// them if the "persist_bookmarks" setting is set.
+func setBookmarks(v *backend.View, rs []text.Region) error {
+	markBookmarks(v, rs)
+	return saveBookmarks(v)
+}
+
+func markBookmarks(v *backend.View, rs []text.Region) {
+	if len(rs) == 0 {
+		v.EraseRegions(bookmarksKey)
+		return
+	}
+	v.AddRegions(bookmarksKey, rs, "bookmark", "bookmark", render.DRAW_EMPTY|render.DRAW_NO_FILL|render.PERSISTENT)
+}
+
+// Run executes the ToggleBookmark command.
+func (c *ToggleBookmark) Run(v *backend.View, e *backend.Edit) error {
+	rowOf := func(p int) int {
+		row, _ := v.RowCol(p)
+		return row
+	}
+	// Whether a line is bookmarked is decided from the bookmarks
+	// before the toggle, and each line is toggled only once.
+	bs := v.GetRegions(bookmarksKey)
+	marked := make(map[int]bool)
+	for _, b := range bs {
+		marked[rowOf(b.Begin())] = true
+	}
+	toggled := make(map[int]bool)
+	var added []text.Region
+	for _, r := range v.Sel().Regions() {
+		row := rowOf(r.Begin())
+		if toggled[row] {
+			continue
+		}
+		toggled[row] = true
+		if !marked[row] {
+			added = append(added, r)
+		}
+	}
+	var kept []text.Region
+	for _, b := range bs {
+		if !toggled[rowOf(b.Begin())] {
+			kept = append(kept, b)
+		}
+	}
+	return setBookmarks(v, append(kept, added...))
+}
+
+// Run executes the NextBookmark command.
+func (c *NextBookmark) Run(v *backend.View, e *backend.Edit) error {
+	gotoBookmark(v, true)
+	return nil
+}
+
+// Run executes the PrevBookmark command.
+func (c *PrevBookmark) Run(v *backend.View, e *backend.Edit) error {
+	gotoBookmark(v, false)
+	return nil
+}
+
+// gotoBookmark selects the bookmark after or before
+// the selections, wrapping around the buffer.
+func gotoBookmark(v *backend.View, forward bool) {
+	bs := v.GetRegions(bookmarksKey)
+	if len(bs) == 0 {
+		return
+	}
+	// Neither the bookmarks nor the selections are
+	// kept sorted by position.
+	sort.Sort(regionSorter(bs))
+	rs := v.Sel().Regions()
+	b := bs[0]
+	if forward {
+		pos := -1
+		for _, r := range rs {
+			pos = text.Max(pos, r.End())
+		}
+		for _, r := range bs {
+			if r.Begin() > pos {
+				b = r
+				break
+			}
+		}
+	} else {
+		pos := v.Size() + 1
+		for _, r := range rs {
+			pos = text.Min(pos, r.Begin())
+		}
+		b = bs[len(bs)-1]
+		for i := len(bs) - 1; i >= 0; i-- {
+			if bs[i].Begin() < pos {
+				b = bs[i]
+				break
+			}
+		}
+	}
+	v.Sel().Clear()
+	v.Sel().Add(b)
+	backend.GetEditor().Frontend().Show(v, b)
+}
+
+// Run executes the SelectAllBookmarks command.
+func (c *SelectAllBookmarks) Run(v *backend.View, e *backend.Edit) error {
+	if bs := v.GetRegions(bookmarksKey); len(bs) > 0 {
+		v.Sel().Clear()
+		v.Sel().AddAll(bs)
+	}
+	return nil
+}
+
+// Run executes the ClearBookmarks command.
+func (c *ClearBookmarks) Run(v *backend.View, e *backend.Edit) error {
+	return setBookmarks(v, nil)
+}
+
+// bookmarksFile returns the file the bookmarks of v are persisted
+// in, or "" if they aren't. It's next to the project file, named
+// like it, with the bookmarksExt extension.
+func bookmarksFile(v *backend.View) string {
+	w := v.Window()
+	if w == nil || v.FileName() == "" || !v.Settings().Bool("persist_bookmarks", false) {
+		return ""
+	}
+	p := w.Project()
+	if p == nil || p.FileName() == "" {
+		return ""
+	}
+	return strings.TrimSuffix(p.FileName(), filepath.Ext(p.FileName())) + bookmarksExt
+}
+
+// readBookmarks reads the bookmarks persisted in file, by file name.
+func readBookmarks(file string) (map[string][]text.Region, error) {
+	m := make(map[string][]text.Region)
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(data, &m)
+}
+
+// saveBookmarks persists the bookmarks of v, if enabled.
+func saveBookmarks(v *backend.View) error {
+	file := bookmarksFile(v)
+	if file == "" {
+		return nil
+	}
+	m, err := readBookmarks(file)
+	if err != nil {
+		return err
+	}
+	if bs := v.GetRegions(bookmarksKey); len(bs) > 0 {
+		m[v.FileName()] = bs
+	} else {
+		delete(m, v.FileName())
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// loadBookmarks restores the persisted bookmarks of v, if enabled.
+func loadBookmarks(v *backend.View) {
+	file := bookmarksFile(v)
+	if file == "" {
+		return
+	}
+	m, err := readBookmarks(file)
+	if err != nil {
+		log.Error("Failed to read the bookmarks in %s: %s", file, err)
+		return
+	}
+	var rs []text.Region
+	for _, r := range m[v.FileName()] {
+		if r.End() <= v.Size() {
+			rs = append(rs, r)
+		}
+	}
+	markBookmarks(v, rs)
+}
+
+func init() {
+	register([]backend.Command{
+		&ToggleBookmark{},
+		&NextBookmark{},
+		&PrevBookmark{},
+		&SelectAllBookmarks{},
+		&ClearBookmarks{},
+	})
+
+	backend.OnLoad.Add(loadBookmarks)
+	// The bookmarks move with the edits, so save where they
+	// are when the file is.
+	backend.OnPostSave.Add(func(v *backend.View) {
+		if err := saveBookmarks(v); err != nil {
+			log.Error("Failed to save the bookmarks of %s: %s", v.FileName(), err)
+		}
+	})
+}
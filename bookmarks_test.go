@@ -0,0 +1,150 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestBookmarks(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "Hello World!\nTest123123\nAbrakadabra\n")
+	v.EndEdit(e)
+
+	tests := []struct {
+		cmd string
+		sel []text.Region
+		exp []text.Region
+	}{
+		{"next_bookmark", []text.Region{{3, 3}}, []text.Region{{3, 3}}},
+		{"toggle_bookmark", []text.Region{{2, 2}, {15, 18}, {30, 30}}, []text.Region{{2, 2}, {15, 18}, {30, 30}}},
+		{"next_bookmark", []text.Region{{3, 3}}, []text.Region{{15, 18}}},
+		{"next_bookmark", []text.Region{{15, 18}}, []text.Region{{30, 30}}},
+		{"next_bookmark", []text.Region{{30, 30}}, []text.Region{{2, 2}}},
+		{"prev_bookmark", []text.Region{{2, 2}}, []text.Region{{30, 30}}},
+		{"prev_bookmark", []text.Region{{20, 20}}, []text.Region{{15, 18}}},
+		{"toggle_bookmark", []text.Region{{14, 14}}, []text.Region{{14, 14}}},
+		{"select_all_bookmarks", []text.Region{{0, 0}}, []text.Region{{2, 2}, {30, 30}}},
+		{"clear_bookmarks", []text.Region{{0, 0}}, []text.Region{{0, 0}}},
+		{"select_all_bookmarks", []text.Region{{5, 5}}, []text.Region{{5, 5}}},
+	}
+	for i, test := range tests {
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		ed.CommandHandler().RunTextCommand(v, test.cmd, nil)
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, sr)
+		}
+	}
+
+	// Bookmarks toggled out of order and unsorted selections.
+	for _, p := range []int{30, 2} {
+		v.Sel().Clear()
+		v.Sel().Add(text.Region{p, p})
+		ed.CommandHandler().RunTextCommand(v, "toggle_bookmark", nil)
+	}
+	tests = []struct {
+		cmd string
+		sel []text.Region
+		exp []text.Region
+	}{
+		{"next_bookmark", []text.Region{{0, 0}}, []text.Region{{2, 2}}},
+		{"prev_bookmark", []text.Region{{37, 37}}, []text.Region{{30, 30}}},
+		{"next_bookmark", []text.Region{{20, 20}, {3, 3}}, []text.Region{{30, 30}}},
+		{"prev_bookmark", []text.Region{{20, 20}, {3, 3}}, []text.Region{{2, 2}}},
+	}
+	for i, test := range tests {
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		ed.CommandHandler().RunTextCommand(v, test.cmd, nil)
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.exp) {
+			t.Errorf("Unsorted test %d: Expected %v, but got %v", i, test.exp, sr)
+		}
+	}
+	ed.CommandHandler().RunTextCommand(v, "clear_bookmarks", nil)
+
+	// Several cursors on a line toggle it once.
+	toggles := []struct {
+		sel []text.Region
+		exp []text.Region
+	}{
+		{[]text.Region{{0, 0}, {2, 2}}, []text.Region{{0, 0}}},
+		{[]text.Region{{2, 2}, {4, 4}, {15, 15}, {17, 17}}, []text.Region{{15, 15}}},
+		{[]text.Region{{13, 13}, {16, 16}}, []text.Region{}},
+	}
+	for i, test := range toggles {
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		ed.CommandHandler().RunTextCommand(v, "toggle_bookmark", nil)
+		if bs := v.GetRegions(bookmarksKey); !reflect.DeepEqual(bs, test.exp) {
+			t.Errorf("Toggle test %d: Expected the bookmarks %v, but got %v", i, test.exp, bs)
+		}
+	}
+
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{15, 15})
+	ed.CommandHandler().RunTextCommand(v, "toggle_bookmark", nil)
+	e = v.BeginEdit()
+	v.Insert(e, 0, "abc")
+	v.EndEdit(e)
+	if exp, bs := []text.Region{{18, 18}}, v.GetRegions(bookmarksKey); !reflect.DeepEqual(bs, exp) {
+		t.Errorf("Expected the bookmarks to move with the edit to %v, but got %v", exp, bs)
+	}
+}
+
+func TestBookmarksPersist(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+
+	dir, err := ioutil.TempDir("", "bookmarks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(file, []byte("Hello World!\nTest123123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Project().SaveAs(filepath.Join(dir, "test.sublime-project")); err != nil {
+		t.Fatal(err)
+	}
+	w.Settings().Set("persist_bookmarks", true)
+
+	v := w.OpenFile(file, 0)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{15, 15})
+	ed.CommandHandler().RunTextCommand(v, "toggle_bookmark", nil)
+	if _, err := os.Stat(filepath.Join(dir, "test"+bookmarksExt)); err != nil {
+		t.Errorf("Expected the bookmarks to be saved, but got %s", err)
+	}
+	v.Close()
+
+	v = w.OpenFile(file, 0)
+	defer v.Close()
+	if exp, bs := []text.Region{{15, 15}}, v.GetRegions(bookmarksKey); !reflect.DeepEqual(bs, exp) {
+		t.Errorf("Expected the bookmarks %v to be restored, but got %v", exp, bs)
+	}
+}
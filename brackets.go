@@ -0,0 +1,209 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+// The number of characters read at a time while scanning.
+const bracketChunk = 1024
+
+// Scopes whose brackets don't pair with the ones outside them.
+const bracketIgnoreScopes = "string, comment"
+
+type (
+	// bracketScanner finds matching brackets in a view, reading the
+	// buffer a chunk at a time from the starting point. Brackets in
+	// strings and comments only pair with brackets in the same kind
+	// of scope as the one the scan started from.
+	//
+	// The bracket pairs are read from the "bracket_pairs" setting, a
+	// list of two character strings such as "()", so they can be
+	// configured per syntax.
+	bracketScanner struct {
+		v *backend.View
+		// Closing brackets by opening ones and vice versa.
+		opening, closing map[rune]rune
+		// The maximum number of characters a scan reads,
+		// no limit if 0.
+		limit int
+	}
+)
+
+var defaultBracketPairs = []string{"()", "[]", "{}"}
+
+func newBracketScanner(v *backend.View) *bracketScanner {
+	s := &bracketScanner{
+		v:       v,
+		opening: make(map[rune]rune),
+		closing: make(map[rune]rune),
+	}
+	for _, p := range bracketPairs(v) {
+		if rs := []rune(p); len(rs) == 2 && rs[0] != rs[1] {
+			s.opening[rs[0]] = rs[1]
+			s.closing[rs[1]] = rs[0]
+		}
+	}
+	return s
+}
+
+// bracketPairs returns the "bracket_pairs" setting of v.
+func bracketPairs(v *backend.View) []string {
+	switch ps := v.Settings().Get("bracket_pairs").(type) {
+	case []string:
+		return ps
+	case []interface{}:
+		var ret []string
+		for _, p := range ps {
+			if s, ok := p.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	}
+	return defaultBracketPairs
+}
+
+// at returns the character at pos, or 0 if pos is
+// outside of the buffer.
+func (s *bracketScanner) at(pos int) rune {
+	if pos < 0 || pos >= s.v.Size() {
+		return 0
+	}
+	return s.v.SubstrR(text.Region{pos, pos + 1})[0]
+}
+
+func (s *bracketScanner) isOpening(r rune) bool {
+	_, ok := s.opening[r]
+	return ok
+}
+
+func (s *bracketScanner) isClosing(r rune) bool {
+	_, ok := s.closing[r]
+	return ok
+}
+
+// ignored reports whether the character at pos is in
+// a string or a comment.
+func (s *bracketScanner) ignored(pos int) bool {
+	return scopeMatches(s.v.ScopeName(pos), bracketIgnoreScopes)
+}
+
+// each calls fn with the characters from pos on, going forward
+// or backwards, until fn returns false, the buffer ends or the
+// scan limit is reached.
+func (s *bracketScanner) each(pos int, forward bool, fn func(p int, r rune) bool) {
+	size := s.v.Size()
+	if s.limit > 0 {
+		inner := fn
+		n := 0
+		fn = func(p int, r rune) bool {
+			n++
+			return n <= s.limit && inner(p, r)
+		}
+	}
+	if forward {
+		for ; pos < size; pos += bracketChunk {
+			end := pos + bracketChunk
+			if end > size {
+				end = size
+			}
+			for i, r := range s.v.SubstrR(text.Region{pos, end}) {
+				if !fn(pos+i, r) {
+					return
+				}
+			}
+		}
+		return
+	}
+	if pos >= size {
+		pos = size - 1
+	}
+	for ; pos >= 0; pos -= bracketChunk {
+		start := pos - bracketChunk + 1
+		if start < 0 {
+			start = 0
+		}
+		rs := s.v.SubstrR(text.Region{start, pos + 1})
+		for i := len(rs) - 1; i >= 0; i-- {
+			if !fn(start+i, rs[i]) {
+				return
+			}
+		}
+	}
+}
+
+// findClose returns the position of the bracket closing the
+// opening bracket at pos, or -1 if there is none.
+func (s *bracketScanner) findClose(pos int) int {
+	open := s.at(pos)
+	close, ok := s.opening[open]
+	if !ok {
+		return -1
+	}
+	return s.match(pos, open, close, true)
+}
+
+// findOpen returns the position of the bracket opening the
+// closing bracket at pos, or -1 if there is none.
+func (s *bracketScanner) findOpen(pos int) int {
+	close := s.at(pos)
+	open, ok := s.closing[close]
+	if !ok {
+		return -1
+	}
+	return s.match(pos, close, open, false)
+}
+
+// match scans from the bracket b at pos for its counterpart m.
+func (s *bracketScanner) match(pos int, b, m rune, forward bool) int {
+	in := s.ignored(pos)
+	step := 1
+	if !forward {
+		step = -1
+	}
+	depth, ret := 0, -1
+	s.each(pos+step, forward, func(p int, r rune) bool {
+		if r != b && r != m || s.ignored(p) != in {
+			return true
+		}
+		if r == b {
+			depth++
+		} else if depth == 0 {
+			ret = p
+			return false
+		} else {
+			depth--
+		}
+		return true
+	})
+	return ret
+}
+
+// enclosing returns the position of the innermost opening
+// bracket before pos which isn't closed before pos, or -1
+// if there is none.
+func (s *bracketScanner) enclosing(pos int) int {
+	in := s.ignored(pos)
+	depth := make(map[rune]int)
+	ret := -1
+	s.each(pos-1, false, func(p int, r rune) bool {
+		if !s.isOpening(r) && !s.isClosing(r) || s.ignored(p) != in {
+			return true
+		}
+		if open, ok := s.closing[r]; ok {
+			depth[open]++
+		} else if depth[r] == 0 {
+			ret = p
+			return false
+		} else {
+			depth[r]--
+		}
+		return true
+	})
+	return ret
+}
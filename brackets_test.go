@@ -0,0 +1,107 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestBracketScanner(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	// 0         1         2         3
+	// 0123456789012345678901234567890123456789
+	// f(a, ")", g[0]) // (}
+	// {"(" (x)}
+	setTestSyntax(t, v, "f(a, \")\", g[0]) // (}\n{\"(\" (x)}\n")
+
+	tests := []struct {
+		pos        int
+		open       bool
+		exp        int
+		enclosing  int
+		encloseExp int
+	}{
+		{1, true, 14, 3, 1},
+		{14, false, 1, 12, 11},
+		{11, true, 13, 9, 1},
+		{6, false, -1, 6, -1},
+		{19, true, -1, 20, 19},
+		{22, true, 30, 26, 22},
+		{30, false, 22, 29, 27},
+	}
+	bs := newBracketScanner(v)
+	for i, test := range tests {
+		var p int
+		if test.open {
+			p = bs.findClose(test.pos)
+		} else {
+			p = bs.findOpen(test.pos)
+		}
+		if p != test.exp {
+			t.Errorf("Test %d: Expected the match of %d to be %d, but got %d", i, test.pos, test.exp, p)
+		}
+		if p := bs.enclosing(test.enclosing); p != test.encloseExp {
+			t.Errorf("Test %d: Expected %d to be enclosed by %d, but got %d", i, test.enclosing, test.encloseExp, p)
+		}
+	}
+}
+
+func TestBracketPairs(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	long := strings.Repeat("x", 3*bracketChunk)
+	e := v.BeginEdit()
+	v.Insert(e, 0, "<a("+long+")>")
+	v.EndEdit(e)
+	end := v.Size() - 1
+
+	bs := newBracketScanner(v)
+	if p := bs.findClose(2); p != end-1 {
+		t.Errorf("Expected %d, but got %d", end-1, p)
+	}
+	if p := bs.findClose(0); p != -1 {
+		t.Errorf("Expected <> not to be brackets by default, but got %d", p)
+	}
+
+	v.Settings().Set("bracket_pairs", []interface{}{"<>"})
+	bs = newBracketScanner(v)
+	if p := bs.findClose(0); p != end {
+		t.Errorf("Expected %d, but got %d", end, p)
+	}
+	if p := bs.findOpen(end); p != 0 {
+		t.Errorf("Expected 0, but got %d", p)
+	}
+	if p := bs.findClose(2); p != -1 {
+		t.Errorf("Expected () not to be brackets, but got %d", p)
+	}
+
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{end, end})
+	var fe front
+	ed.SetFrontend(&fe)
+	ed.CommandHandler().RunTextCommand(v, "move_to", backend.Args{"to": "brackets"})
+	if sr := v.Sel().Regions(); len(sr) != 1 || sr[0] != (text.Region{1, 1}) {
+		t.Errorf("Expected move_to to use the configured pairs, but got %v", sr)
+	}
+}
@@ -7,6 +7,7 @@ package commands
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/limetext/backend"
 )
@@ -107,6 +108,86 @@ func (c *LowerCase) Run(v *backend.View, e *backend.Edit) error {
 	return nil
 }
 
+// splitWords splits s into the words of an identifier or
+// phrase, e.g. "fooBar", "foo_bar" and "foo bar" all give
+// ["foo", "bar"] and "HTTPServer" gives ["HTTP", "Server"].
+func splitWords(s string) (words []string) {
+	rs := []rune(s)
+	start := 0
+	for i, r := range rs {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			if i > start {
+				words = append(words, string(rs[start:i]))
+			}
+			start = i + 1
+		case i > start && unicode.IsUpper(r):
+			prev := rs[i-1]
+			// The last upper case letter of an acronym
+			// starts the next word.
+			if unicode.IsLower(prev) || unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1]) {
+				words = append(words, string(rs[start:i]))
+				start = i
+			}
+		}
+	}
+	if start < len(rs) {
+		words = append(words, string(rs[start:]))
+	}
+	return
+}
+
+func upperFirst(s string) string {
+	for i, r := range s {
+		return string(unicode.ToUpper(r)) + s[i+utf8.RuneLen(r):]
+	}
+	return s
+}
+
+// matchCase returns repl cased like match. All upper and all lower
+// case, Title, camelCase, PascalCase and snake_case matches are
+// recognised, repl is returned unchanged for anything else.
+func matchCase(match, repl string) string {
+	var upper, lower bool
+	for _, r := range match {
+		upper = upper || unicode.IsUpper(r)
+		lower = lower || unicode.IsLower(r)
+	}
+	first, n := utf8.DecodeRuneInString(match)
+	rest := match[n:]
+	words := splitWords(repl)
+	for i := range words {
+		words[i] = strings.ToLower(words[i])
+	}
+	switch {
+	case !upper && !lower:
+		return repl
+	case strings.Contains(match, "_"):
+		s := strings.Join(words, "_")
+		if !lower {
+			s = strings.ToUpper(s)
+		}
+		return s
+	case !lower:
+		return strings.ToUpper(repl)
+	case !upper:
+		return strings.ToLower(repl)
+	case unicode.IsLower(first):
+		for i := 1; i < len(words); i++ {
+			words[i] = upperFirst(words[i])
+		}
+		return strings.Join(words, "")
+	case unicode.IsUpper(first) && strings.ToLower(rest) != rest:
+		for i := range words {
+			words[i] = upperFirst(words[i])
+		}
+		return strings.Join(words, "")
+	case unicode.IsUpper(first):
+		return upperFirst(repl)
+	}
+	return repl
+}
+
 func init() {
 	register([]backend.Command{
 		&TitleCase{},
@@ -200,3 +200,27 @@ func TestLowerCase(t *testing.T) {
 
 	runCaseTest("lower_case", &tests, t)
 }
+
+func TestMatchCase(t *testing.T) {
+	tests := []struct {
+		match, repl, exp string
+	}{
+		{"foo", "barBaz", "barbaz"},
+		{"FOO", "barBaz", "BARBAZ"},
+		{"Foo", "barBaz", "BarBaz"},
+		{"Foo", "bar baz", "Bar baz"},
+		{"fooName", "bar_baz", "barBaz"},
+		{"FooName", "bar_baz", "BarBaz"},
+		{"foo_name", "barBaz", "bar_baz"},
+		{"FOO_NAME", "barBaz", "BAR_BAZ"},
+		{"FOO_NAME", "HTTPServer", "HTTP_SERVER"},
+		{"fooName", "HTTPServer", "httpServer"},
+		{"123", "barBaz", "barBaz"},
+		{"Ärger", "übel", "Übel"},
+	}
+	for i, test := range tests {
+		if out := matchCase(test.match, test.repl); out != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, out)
+		}
+	}
+}
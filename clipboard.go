@@ -7,6 +7,7 @@ package commands
 import (
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/limetext/backend"
 	"github.com/limetext/text"
@@ -18,13 +19,18 @@ type (
 	// top to bottom of the file, separated by newlines
 	Copy struct {
 		backend.DefaultCommand
+		// The register to copy to instead of the clipboard, if any.
+		Register string
 	}
 
 	// Cut copies the current selection to the clipboard, removing it from the
 	// buffer. If there are multiple selections, they are concatenated in order
-	// from top to bottom of the file, separated by newlines.
+	// from top to bottom of the file, separated by newlines. The text is also
+	// added to the kill ring.
 	Cut struct {
 		backend.DefaultCommand
+		// The register to cut to instead of the clipboard, if any.
+		Register string
 	}
 
 	// Paste pastes the contents of the clipboard, overwriting the current
@@ -32,9 +38,27 @@ type (
 	// split into lines. If the number of lines equals the number of selections,
 	// the lines are pasted separately into each selection in order from top to
 	// bottom of the file. Otherwise the entire clipboard is pasted over every
-	// selection.
+	// selection. Text copied from as many selections as there are is pasted
+	// one selection's text into each, even if it has new lines.
 	Paste struct {
 		backend.DefaultCommand
+		// The register to paste from instead of the clipboard, if any.
+		Register string
+	}
+
+	// PasteAndIndent pastes like Paste, indenting the pasted lines so the
+	// least indented one has the indentation of the line pasted into.
+	PasteAndIndent struct {
+		backend.DefaultCommand
+	}
+
+	// copiedPieces holds the regions' text behind the clipboard
+	// content, so Paste can give each cursor its own text even if
+	// it has new lines.
+	copiedPieces struct {
+		lock   sync.Mutex
+		text   string
+		pieces []string
 	}
 )
 
@@ -56,8 +80,10 @@ func getRegions(v *backend.View, cut bool) *text.RegionSet {
 	return rs
 }
 
-func getSelForCopy(v *backend.View, rs *text.RegionSet) (s string, ex bool) {
-	ss := make([]string, rs.Len())
+// getSelPieces returns the text to copy of each region of rs. Whole
+// lines copied from cursors get a new line if they don't end with one.
+func getSelPieces(v *backend.View, rs *text.RegionSet) (ss []string, ex bool) {
+	ss = make([]string, rs.Len())
 
 	for i, r := range rs.Regions() {
 		sub := v.Substr(r)
@@ -70,68 +96,161 @@ func getSelForCopy(v *backend.View, rs *text.RegionSet) (s string, ex bool) {
 		ss[i] = sub
 	}
 
-	s = strings.Join(ss, "\n")
-
 	return
 }
 
 // Run executes the Copy command.
 func (c *Copy) Run(v *backend.View, e *backend.Edit) error {
 	rs := getRegions(v, false)
-	s, ex := getSelForCopy(v, rs)
-
-	cb := backend.GetEditor().Clipboard()
-	cb.Set(s, ex)
+	ss, ex := getSelPieces(v, rs)
+	if c.Register != "" {
+		return setRegister(c.Register, ss, ex)
+	}
+	setClipboard(v, ss, ex)
 
 	return nil
 }
 
 // Run executes the Cut command.
 func (c *Cut) Run(v *backend.View, e *backend.Edit) error {
-	s, ex := getSelForCopy(v, getRegions(v, false))
+	if err := checkRegister(c.Register, true); err != nil {
+		return err
+	}
+	ss, ex := getSelPieces(v, getRegions(v, false))
 
 	rs := getRegions(v, true)
 	regions := rs.Regions()
+	done := kills.add(v, killRegions(v, regions), false)
 	sort.Sort(sort.Reverse(regionSorter(regions)))
 
 	for _, r := range regions {
 		v.Erase(e, r)
 	}
-
-	cb := backend.GetEditor().Clipboard()
-	cb.Set(s, ex)
+	done()
+	if c.Register != "" {
+		return setRegister(c.Register, ss, ex)
+	}
+	setClipboard(v, ss, ex)
 
 	return nil
 }
 
 // Run executes the Paste command.
 func (c *Paste) Run(v *backend.View, e *backend.Edit) error {
-	cb := backend.GetEditor().Clipboard()
+	if c.Register != "" {
+		ss, ex, err := getRegister(v, c.Register)
+		if err != nil {
+			return err
+		}
+		pastePieces(v, e, ss, ex)
+		return nil
+	}
+	ss, ex := clipboardPieces()
+	pastePieces(v, e, ss, ex)
+
+	return nil
+}
+
+// The text of each region copied or cut last.
+var copied copiedPieces
+
+func (c *copiedPieces) set(s string, ss []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.text, c.pieces = s, ss
+}
+
+// piecesOf returns the pieces of s, or nil if s isn't what was
+// copied last, like when it was copied by another application.
+func (c *copiedPieces) piecesOf(s string) []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.pieces == nil || s != c.text {
+		return nil
+	}
+	return append([]string(nil), c.pieces...)
+}
+
+// setClipboard puts the pieces ss joined by new lines on the
+// clipboard, adding them to the clipboard history.
+func setClipboard(v *backend.View, ss []string, ex bool) {
+	s := strings.Join(ss, "\n")
+	copied.set(s, ss)
+	backend.GetEditor().Clipboard().Set(s, ex)
+	clips.add(clipEntry{s, ex}, clipboardHistorySize(v))
+}
+
+// Run executes the PasteAndIndent command.
+func (c *PasteAndIndent) Run(v *backend.View, e *backend.Edit) error {
+	ss, ex := clipboardPieces()
+	rs := pasteRegions(v)
+	texts := pasteTexts(ss, ex, len(rs))
+	for i, r := range rs {
+		texts[i] = reindent(v, r, texts[i], ex)
+	}
+	insertPasted(v, e, rs, texts, ex)
+
+	return nil
+}
+
+// clipboardPieces returns the clipboard content, as the pieces
+// it was copied from if it's what was copied last.
+func clipboardPieces() ([]string, bool) {
+	s, ex := backend.GetEditor().Clipboard().Get()
+	if ss := copied.piecesOf(s); ss != nil {
+		return ss, ex
+	}
+	return []string{s}, ex
+}
 
+// pasteRegions returns the sorted regions of v's selection.
+func pasteRegions(v *backend.View) []text.Region {
 	rs := &text.RegionSet{}
 	regions := v.Sel().Regions()
 	sort.Sort(regionSorter(regions))
 	rs.AddAll(regions)
+	return rs.Regions()
+}
 
-	s, ex := cb.Get()
-
-	ss := strings.Split(s, "\n")
-	split := !ex && len(ss) == rs.Len()
-
-	for i := rs.Len() - 1; i >= 0; i-- {
-		r := rs.Get(i)
+// pasteTexts returns the text to paste into each of n regions from
+// the pieces ss. That's a piece each if there are as many as regions,
+// or else a line each if the text isn't auto-expanded and has as many
+// lines. Otherwise it's all the text for every region.
+func pasteTexts(ss []string, ex bool, n int) []string {
+	if len(ss) > 1 && len(ss) == n {
+		return ss
+	}
+	s := strings.Join(ss, "\n")
+	if lines := strings.Split(s, "\n"); !ex && len(lines) == n {
+		return lines
+	}
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = s
+	}
+	return texts
+}
 
-		if split {
-			v.Replace(e, r, ss[i])
-		} else if !ex {
-			v.Replace(e, r, s)
+// insertPasted replaces each region of rs with its text, or inserts
+// the text before the region's line if it's auto-expanded.
+func insertPasted(v *backend.View, e *backend.Edit, rs []text.Region, texts []string, ex bool) {
+	for i := len(rs) - 1; i >= 0; i-- {
+		if ex {
+			v.Insert(e, v.FullLineR(rs[i]).Begin(), texts[i])
 		} else {
-			l := v.FullLineR(r)
-			v.Insert(e, l.Begin(), s)
+			v.Replace(e, rs[i], texts[i])
 		}
 	}
+}
 
-	return nil
+// pastePieces pastes the pieces ss at the selections of v like
+// Paste does, ex being whether they are auto-expanded whole lines.
+func pastePieces(v *backend.View, e *backend.Edit, ss []string, ex bool) {
+	if len(ss) == 0 {
+		return
+	}
+	rs := pasteRegions(v)
+	insertPasted(v, e, rs, pasteTexts(ss, ex, len(rs)), ex)
 }
 
 func init() {
@@ -139,5 +258,6 @@ func init() {
 		&Copy{},
 		&Cut{},
 		&Paste{},
+		&PasteAndIndent{},
 	})
 }
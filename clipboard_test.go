@@ -565,3 +565,129 @@ func TestPaste(t *testing.T) {
 
 	runClipboardTest("paste", &tests, t)
 }
+
+func TestPasteRoundTrip(t *testing.T) {
+	ed := GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	cb := &dummyClipboard{}
+	ed.UseClipboard(cb)
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	set := func(s string, rs ...text.Region) {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{A: 0, B: v.Size()})
+		v.Insert(e, 0, s)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(rs)
+	}
+	set("a1\na2\nb1\nb2\nc1\nc2\n", text.Region{A: 0, B: 5}, text.Region{A: 6, B: 11}, text.Region{A: 12, B: 17})
+	ed.CommandHandler().RunTextCommand(v, "copy", nil)
+
+	tests := []struct {
+		clip   string
+		sel    []text.Region
+		expBuf string
+	}{
+		{"", []text.Region{{1, 1}, {3, 3}, {5, 5}}, "xa1\na2\nyb1\nb2\nzc1\nc2\n"},
+		{"", []text.Region{{1, 1}, {3, 3}}, "xa1\na2\nb1\nb2\nc1\nc2\nya1\na2\nb1\nb2\nc1\nc2\nz\n"},
+		{"p\nq\nr", []text.Region{{1, 1}, {3, 3}, {5, 5}}, "xp\nyq\nzr\n"},
+	}
+	for i, test := range tests {
+		if test.clip != "" {
+			cb.Set(test.clip, false)
+		}
+		set("x\ny\nz\n", test.sel...)
+		ed.CommandHandler().RunTextCommand(v, "paste", nil)
+		if b := v.Substr(text.Region{A: 0, B: v.Size()}); b != test.expBuf {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.expBuf, b)
+		}
+	}
+}
+
+func TestPasteAndIndent(t *testing.T) {
+	ed := GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	cb := &dummyClipboard{}
+	ed.UseClipboard(cb)
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []struct {
+		buf     string
+		clip    string
+		ex      bool
+		spaces  bool
+		tabSize int
+		sel     []text.Region
+		expBuf  string
+	}{
+		{
+			"func f() {\n\t\n}",
+			"if x {\n\ty()\n}",
+			false, false, 4,
+			[]text.Region{{12, 12}},
+			"func f() {\n\tif x {\n\t\ty()\n\t}\n}",
+		},
+		{
+			"func f() {\n\t\n}",
+			"\t\t\tif x {\n\t\t\t\ty()\n\n\t\t\t}",
+			false, false, 4,
+			[]text.Region{{12, 12}},
+			"func f() {\n\tif x {\n\t\ty()\n\n\t}\n}",
+		},
+		{
+			"a\n\tb\n",
+			"\t\tx\n\t\t\ty\n",
+			true, false, 4,
+			[]text.Region{{4, 4}},
+			"a\n\tx\n\t\ty\n\tb\n",
+		},
+		{
+			"a\n    b\n",
+			"\tx\n\t\ty\n",
+			true, true, 4,
+			[]text.Region{{4, 4}},
+			"a\n    x\n        y\n    b\n",
+		},
+		{
+			"  a\n",
+			"x\n\ty\nz",
+			false, true, 2,
+			[]text.Region{{3, 3}},
+			"  ax\n    y\n  z\n",
+		},
+		{
+			"\ta\n\t\tb\n",
+			"x\n y",
+			false, false, 4,
+			[]text.Region{{2, 2}, {6, 6}},
+			"\tax\n\t\tby\n",
+		},
+	}
+	for i, test := range tests {
+		v.Settings().Set("translate_tabs_to_spaces", test.spaces)
+		v.Settings().Set("tab_size", test.tabSize)
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{A: 0, B: v.Size()})
+		v.Insert(e, 0, test.buf)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		cb.Set(test.clip, test.ex)
+
+		ed.CommandHandler().RunTextCommand(v, "paste_and_indent", nil)
+		if b := v.Substr(text.Region{A: 0, B: v.Size()}); b != test.expBuf {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.expBuf, b)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/limetext/backend"
+)
+
+type (
+	// PasteFromHistory command pastes an earlier entry of the
+	// clipboard history like Paste does. Without an index the
+	// entries are listed by the frontend instead.
+	PasteFromHistory struct {
+		backend.DefaultCommand
+		// The entry to paste, 0 being the most recent one.
+		Index int
+	}
+
+	// clipHistory holds the text copied or cut last, most
+	// recent first and without duplicates.
+	clipHistory struct {
+		lock    sync.Mutex
+		entries []clipEntry
+	}
+
+	clipEntry struct {
+		text string
+		// Whether the text is whole lines auto-expanded
+		// from cursors.
+		expanded bool
+	}
+)
+
+// The clipboard history of the editor.
+var clips clipHistory
+
+func clipboardHistorySize(v *backend.View) int {
+	return v.Settings().Int("clipboard_history_size", 16)
+}
+
+// add makes c the most recent entry, moving it to the
+// front if it's in the history already.
+func (h *clipHistory) add(c clipEntry, size int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for i, e := range h.entries {
+		if e == c {
+			h.entries = append(h.entries[:i], h.entries[i+1:]...)
+			break
+		}
+	}
+	h.entries = append([]clipEntry{c}, h.entries...)
+	if len(h.entries) > size {
+		h.entries = h.entries[:size]
+	}
+}
+
+func (h *clipHistory) get(i int) (clipEntry, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if i < 0 || i >= len(h.entries) {
+		return clipEntry{}, false
+	}
+	return h.entries[i], true
+}
+
+// list returns the entries, one per line with their index
+// and first line.
+func (h *clipHistory) list() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	var buf bytes.Buffer
+	for i, e := range h.entries {
+		line := strings.SplitN(e.text, "\n", 2)[0]
+		if rs := []rune(line); len(rs) > 60 {
+			line = string(rs[:60]) + "…"
+		}
+		fmt.Fprintf(&buf, "%d: %s\n", i, line)
+	}
+	return buf.String()
+}
+
+// Run executes the PasteFromHistory command.
+func (c *PasteFromHistory) Run(v *backend.View, e *backend.Edit) error {
+	fe := backend.GetEditor().Frontend()
+	if c.Index < 0 {
+		if l := clips.list(); l != "" {
+			fe.MessageDialog(l)
+		}
+		return nil
+	}
+	clip, ok := clips.get(c.Index)
+	if !ok {
+		return fmt.Errorf("paste_from_history: No entry %d in the clipboard history", c.Index)
+	}
+	pastePieces(v, e, []string{clip.text}, clip.expanded)
+	return nil
+}
+
+// Default returns the default values of the PasteFromHistory arguments.
+func (c *PasteFromHistory) Default(key string) interface{} {
+	if key == "index" {
+		return -1
+	}
+	return nil
+}
+
+func init() {
+	register([]backend.Command{
+		&PasteFromHistory{},
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestPasteFromHistory(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	ed.UseClipboard(&dummyClipboard{})
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	clips.lock.Lock()
+	clips.entries = nil
+	clips.lock.Unlock()
+	v.Settings().Set("clipboard_history_size", 3)
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc def\nghi")
+	v.EndEdit(e)
+
+	for _, sel := range [][]text.Region{{{0, 3}}, {{4, 7}}, {{0, 3}}, {{9, 9}}, {{4, 7}, {8, 11}}} {
+		v.Sel().Clear()
+		v.Sel().AddAll(sel)
+		ch.RunTextCommand(v, "copy", nil)
+	}
+	exp := []clipEntry{{"def\nghi", false}, {"ghi\n", true}, {"abc", false}}
+	if !reflect.DeepEqual(clips.entries, exp) {
+		t.Errorf("Expected the history %v, but got %v", exp, clips.entries)
+	}
+
+	ch.RunTextCommand(v, "paste_from_history", nil)
+	if exp := "0: def\n1: ghi\n2: abc\n"; fe.message != exp {
+		t.Errorf("Expected the history to be listed as %q, but got %q", exp, fe.message)
+	}
+
+	tests := []struct {
+		index  int
+		sel    []text.Region
+		expBuf string
+	}{
+		{2, []text.Region{{11, 11}}, "abc def\nghiabc"},
+		{1, []text.Region{{1, 1}}, "ghi\nabc def\nghi"},
+		{0, []text.Region{{0, 0}, {8, 8}}, "defabc def\nghighi"},
+		{0, []text.Region{{0, 0}}, "def\nghiabc def\nghi"},
+		{3, []text.Region{{0, 0}}, "abc def\nghi"},
+	}
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, "abc def\nghi")
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		ch.RunTextCommand(v, "paste_from_history", backend.Args{"index": test.index})
+		if b := v.Substr(text.Region{0, v.Size()}); b != test.expBuf {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.expBuf, b)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/render"
+	"github.com/limetext/text"
+)
+
+type (
+	// CopyAsHTML command copies the selections, or the whole buffer
+	// if they are empty, as HTML coloured like the view using inline
+	// styles.
+	CopyAsHTML struct {
+		backend.DefaultCommand
+		// The file to also write the HTML to, if any.
+		Path string
+	}
+
+	// CopyAsRTF command copies the selections, or the whole buffer
+	// if they are empty, as RTF coloured like the view.
+	CopyAsRTF struct {
+		backend.DefaultCommand
+		// The file to also write the RTF to, if any.
+		Path string
+	}
+
+	// styledRun is text with the same scope, and
+	// so the same flavour.
+	styledRun struct {
+		text    string
+		flavour render.Flavour
+	}
+
+	// styledText is the text of the regions to copy, as
+	// styled runs, along with the styling of the view.
+	styledText struct {
+		regions  [][]styledRun
+		global   render.Settings
+		font     string
+		fontSize int
+	}
+)
+
+// newStyledText splits the text of the non-empty selections of v,
+// or the whole buffer if there are none, into runs styled by v's
+// colour scheme.
+func newStyledText(v *backend.View) *styledText {
+	scheme := backend.GetEditor().GetColorScheme(v.Settings().String("color_scheme", ""))
+	st := &styledText{
+		global:   scheme.GlobalSettings(),
+		font:     v.Settings().String("font_face", "monospace"),
+		fontSize: v.Settings().Int("font_size", 12),
+	}
+
+	var rs []text.Region
+	for _, r := range pasteRegions(v) {
+		if !r.Empty() {
+			rs = append(rs, r)
+		}
+	}
+	if len(rs) == 0 {
+		rs = []text.Region{{0, v.Size()}}
+	}
+
+	flavours := make(map[string]render.Flavour)
+	flavour := func(scope string) render.Flavour {
+		f, ok := flavours[scope]
+		if !ok {
+			f = scheme.Spice(&render.ViewRegions{Scope: scope})
+			flavours[scope] = f
+		}
+		return f
+	}
+	for _, r := range rs {
+		var runs []styledRun
+		for a := r.Begin(); a < r.End(); {
+			scope := strings.TrimSpace(v.ScopeName(a))
+			b := a + 1
+			for b < r.End() && strings.TrimSpace(v.ScopeName(b)) == scope {
+				b++
+			}
+			runs = append(runs, styledRun{v.Substr(text.Region{a, b}), flavour(scope)})
+			a = b
+		}
+		st.regions = append(st.regions, runs)
+	}
+	return st
+}
+
+// isSet reports whether c was given by the colour scheme.
+func isSet(c render.Colour) bool {
+	return c.A != 0
+}
+
+func cssColour(c render.Colour) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// html returns the text as a pre element, the regions separated by new
+// lines and the runs styled differently from the view in spans.
+func (st *styledText) html() string {
+	var buf bytes.Buffer
+	style := []string{fmt.Sprintf("font-family:%s", st.font), fmt.Sprintf("font-size:%dpt", st.fontSize)}
+	if isSet(st.global.Background) {
+		style = append(style, "background-color:"+cssColour(st.global.Background))
+	}
+	if isSet(st.global.Foreground) {
+		style = append(style, "color:"+cssColour(st.global.Foreground))
+	}
+	fmt.Fprintf(&buf, "<pre style=\"%s\">", strings.Join(style, ";"))
+	for i, runs := range st.regions {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		for _, r := range runs {
+			if s := st.runStyle(r.flavour); s != "" {
+				fmt.Fprintf(&buf, "<span style=\"%s\">%s</span>", s, html.EscapeString(r.text))
+			} else {
+				buf.WriteString(html.EscapeString(r.text))
+			}
+		}
+	}
+	buf.WriteString("</pre>\n")
+	return buf.String()
+}
+
+// runStyle returns the inline style of the runs with the flavour f.
+func (st *styledText) runStyle(f render.Flavour) string {
+	var style []string
+	if isSet(f.Foreground) && f.Foreground != st.global.Foreground {
+		style = append(style, "color:"+cssColour(f.Foreground))
+	}
+	if isSet(f.Background) && f.Background != st.global.Background {
+		style = append(style, "background-color:"+cssColour(f.Background))
+	}
+	if f.Font.Style&render.Bold != 0 {
+		style = append(style, "font-weight:bold")
+	}
+	if f.Font.Style&render.Italic != 0 {
+		style = append(style, "font-style:italic")
+	}
+	if f.Font.Style&render.Underline != 0 {
+		style = append(style, "text-decoration:underline")
+	}
+	return strings.Join(style, ";")
+}
+
+// rtf returns the text as an RTF document, the regions
+// separated by new lines.
+func (st *styledText) rtf() string {
+	// The colour table, the indices start from 1 as
+	// 0 is the default colour.
+	var colours []render.Colour
+	index := func(c render.Colour) int {
+		if !isSet(c) {
+			return 0
+		}
+		c.A = 255
+		for i, o := range colours {
+			if o == c {
+				return i + 1
+			}
+		}
+		colours = append(colours, c)
+		return len(colours)
+	}
+
+	var body bytes.Buffer
+	if bg := index(st.global.Background); bg != 0 {
+		fmt.Fprintf(&body, "\\cb%d", bg)
+	}
+	if fg := index(st.global.Foreground); fg != 0 {
+		fmt.Fprintf(&body, "\\cf%d", fg)
+	}
+	body.WriteString(" ")
+	for i, runs := range st.regions {
+		if i > 0 {
+			body.WriteString("\\line\n")
+		}
+		for _, r := range runs {
+			body.WriteString("{")
+			if isSet(r.flavour.Foreground) {
+				fmt.Fprintf(&body, "\\cf%d", index(r.flavour.Foreground))
+			}
+			if isSet(r.flavour.Background) {
+				fmt.Fprintf(&body, "\\cb%d", index(r.flavour.Background))
+			}
+			if r.flavour.Font.Style&render.Bold != 0 {
+				body.WriteString("\\b")
+			}
+			if r.flavour.Font.Style&render.Italic != 0 {
+				body.WriteString("\\i")
+			}
+			if r.flavour.Font.Style&render.Underline != 0 {
+				body.WriteString("\\ul")
+			}
+			body.WriteString(" ")
+			body.WriteString(rtfEscape(r.text))
+			body.WriteString("}")
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{\\rtf1\\ansi\\deff0{\\fonttbl{\\f0\\fmodern %s;}}{\\colortbl ;", rtfEscape(st.font))
+	for _, c := range colours {
+		fmt.Fprintf(&buf, "\\red%d\\green%d\\blue%d;", c.R, c.G, c.B)
+	}
+	fmt.Fprintf(&buf, "}\\f0\\fs%d", st.fontSize*2)
+	buf.Write(body.Bytes())
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// rtfEscape escapes the characters of s which are special in RTF or
+// aren't ASCII.
+func rtfEscape(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '\\', r == '{', r == '}':
+			buf.WriteRune('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString("\\line\n")
+		case r == '\t':
+			buf.WriteString("\\tab ")
+		case r < 0x80:
+			buf.WriteRune(r)
+		default:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&buf, "\\u%d?", int16(u))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// copyStyled puts s on the clipboard and writes it to
+// path, if set.
+func copyStyled(v *backend.View, s, path string) error {
+	setClipboard(v, []string{s}, false)
+	if path == "" {
+		return nil
+	}
+	if err := ioutil.WriteFile(path, []byte(s), 0644); err != nil {
+		backend.GetEditor().Frontend().ErrorMessage(fmt.Sprintf("Failed to write %s: %s", path, err))
+		return err
+	}
+	return nil
+}
+
+// Run executes the CopyAsHTML command.
+func (c *CopyAsHTML) Run(v *backend.View, e *backend.Edit) error {
+	return copyStyled(v, newStyledText(v).html(), c.Path)
+}
+
+// Run executes the CopyAsRTF command.
+func (c *CopyAsRTF) Run(v *backend.View, e *backend.Edit) error {
+	return copyStyled(v, newStyledText(v).rtf(), c.Path)
+}
+
+func init() {
+	registerByName([]namedCmd{
+		{"copy_as_html", &CopyAsHTML{}},
+		{"copy_as_rtf", &CopyAsRTF{}},
+	})
+}
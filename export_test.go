@@ -0,0 +1,109 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/render"
+	"github.com/limetext/text"
+)
+
+const testSchemeFile = "testdata/Test.tmTheme"
+
+// testScheme colours strings red and comments green and italic.
+type testScheme struct{}
+
+func (s testScheme) Spice(vr *render.ViewRegions) render.Flavour {
+	f := render.Flavour{Foreground: render.Colour{0, 0, 0, 255}}
+	switch {
+	case scopeMatches(vr.Scope, "string"):
+		f.Foreground = render.Colour{255, 0, 0, 255}
+	case scopeMatches(vr.Scope, "comment"):
+		f.Foreground = render.Colour{0, 128, 0, 255}
+		f.Font.Style = render.Italic
+	}
+	return f
+}
+
+func (s testScheme) GlobalSettings() render.Settings {
+	return render.Settings{
+		Foreground: render.Colour{0, 0, 0, 255},
+		Background: render.Colour{255, 255, 255, 255},
+	}
+}
+
+func (s testScheme) Name() string {
+	return "Test"
+}
+
+func TestCopyAs(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	cb := &dummyClipboard{}
+	ed.UseClipboard(cb)
+	ed.AddColorScheme(testSchemeFile, testScheme{})
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	v.Settings().Set("color_scheme", testSchemeFile)
+	setTestSyntax(t, v, "x := \"a<b\" // é\n")
+
+	const pre = `<pre style="font-family:monospace;font-size:12pt;background-color:#ffffff;color:#000000">`
+	const rtf = `{\rtf1\ansi\deff0{\fonttbl{\f0\fmodern monospace;}}{\colortbl ;\red255\green255\blue255;\red0\green0\blue0;`
+	tests := []struct {
+		cmd string
+		sel []text.Region
+		exp string
+	}{
+		{
+			"copy_as_html",
+			nil,
+			pre + `x := <span style="color:#ff0000">&#34;a&lt;b&#34;</span> <span style="color:#008000;font-style:italic">// é</span>` + "\n</pre>\n",
+		},
+		{
+			"copy_as_html",
+			[]text.Region{{0, 1}, {5, 10}},
+			pre + "x\n" + `<span style="color:#ff0000">&#34;a&lt;b&#34;</span></pre>` + "\n",
+		},
+		{
+			"copy_as_rtf",
+			nil,
+			rtf + `\red255\green0\blue0;\red0\green128\blue0;}\f0\fs24\cb1\cf2 {\cf2 x := }{\cf3 "a<b"}{\cf2  }{\cf4\i // \u233?}{\cf2 \line` + "\n}}\n",
+		},
+	}
+	for i, test := range tests {
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		ed.CommandHandler().RunTextCommand(v, test.cmd, nil)
+		if s, _ := cb.Get(); s != test.exp {
+			t.Errorf("Test %d: Expected\n%s\nbut got\n%s", i, test.exp, s)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "copyas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.html")
+	v.Sel().Clear()
+	ed.CommandHandler().RunTextCommand(v, "copy_as_html", backend.Args{"path": path})
+	if data, err := ioutil.ReadFile(path); err != nil {
+		t.Errorf("Expected the HTML to be written to %s, but got %s", path, err)
+	} else if s, _ := cb.Get(); string(data) != s || !strings.HasPrefix(s, pre) {
+		t.Errorf("Expected the HTML in the file and the clipboard, but got %q and %q", data, s)
+	}
+}
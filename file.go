@@ -36,6 +36,9 @@ func (o *PromptOpenFile) Run(w *backend.Window) error {
 	dir := viewDirectory(w.ActiveView())
 	fe := backend.GetEditor().Frontend()
 	files := fe.Prompt("Open file", dir, backend.PROMPT_SELECT_MULTIPLE)
+	if len(files) > 0 {
+		pushJump(w.ActiveView())
+	}
 	for _, file := range files {
 		w.OpenFile(file, 0)
 	}
@@ -0,0 +1,383 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/log"
+	"github.com/limetext/text"
+)
+
+// The name of the view find in files results are written to.
+const findResultsName = "Find Results"
+
+type (
+	// FindInFiles Command searches the files in the project folders
+	// for SearchText and writes the matching lines, grouped by file,
+	// to the "Find Results" view. Starting a new search cancels the
+	// one running in the same window.
+	FindInFiles struct {
+		backend.DefaultCommand
+		SearchText []rune
+		// Comma separated glob patterns, only files matching
+		// one of them are searched when given.
+		Include string
+		// Comma separated glob patterns of files and folders
+		// which are not searched.
+		Exclude string
+		// Whether SearchText is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+	}
+
+	// fileFilter decides which files and folders are searched.
+	fileFilter struct {
+		include, exclude, folderExclude []string
+	}
+
+	// fileResult holds the matching lines of a single file.
+	fileResult struct {
+		path    string
+		lines   []lineMatch
+		matches int
+	}
+
+	lineMatch struct {
+		// 1-based line number.
+		row  int
+		text string
+	}
+)
+
+// Run executes the FindInFiles command.
+func (c *FindInFiles) Run(w *backend.Window) error {
+	if len(c.SearchText) == 0 {
+		return nil
+	}
+	search := string(c.SearchText)
+	f, err := newFinder(search, findOptions{
+		regex:         c.Regex.resolve(w.Settings(), "find_regex"),
+		caseSensitive: c.CaseSensitive.resolve(w.Settings(), "find_case_sensitive"),
+		wholeWord:     c.WholeWord.resolve(w.Settings(), "find_whole_word"),
+	})
+	if err != nil {
+		return err
+	}
+	windowSearch(w).setSearch(search, searchHistorySize(w))
+
+	ctx, cancel := windowSearch(w).startFindInFiles()
+	defer cancel()
+	files, rs := searchFiles(ctx, w, f, c.Include, c.Exclude)
+	// A newer search has been started, leave the results to it.
+	if ctx.Err() != nil {
+		return nil
+	}
+	writeFindResults(w, search, files, rs)
+	return nil
+}
+
+// searchFiles greps the files of w's project folders which pass
+// the include and exclude patterns, returning the number of files
+// searched and the results sorted by path. Files open in w are
+// searched as they are in their view rather than on disk.
+func searchFiles(ctx context.Context, w *backend.Window, f *finder, include, exclude string) (int, []fileResult) {
+	open := make(map[string]string)
+	for _, v := range w.Views() {
+		if fn := v.FileName(); fn != "" {
+			open[fn] = v.Substr(text.Region{0, v.Size()})
+		}
+	}
+
+	ws := wordSeparators(w.Settings())
+	// The walk sends the number of files it found once it's done.
+	walked := make(chan int, 1)
+	paths := make(chan string)
+	results := make(chan fileResult)
+	go func() {
+		defer close(paths)
+		files := 0
+		defer func() { walked <- files }()
+		for _, folder := range w.Project().Folders() {
+			ff := newFileFilter(include, exclude, w.Project().Folder(folder))
+			walkFolder(ctx, folder, ff, func(path string) {
+				files++
+				select {
+				case paths <- path:
+				case <-ctx.Done():
+				}
+			})
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				data, ok := open[path]
+				if !ok {
+					b, err := ioutil.ReadFile(path)
+					if err != nil || isBinary(b) {
+						continue
+					}
+					data = string(b)
+				}
+				if r := grep(f, ws, path, data); r != nil {
+					select {
+					case results <- *r:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var rs []fileResult
+	for r := range results {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].path < rs[j].path
+	})
+	return <-walked, rs
+}
+
+func splitPatterns(s string) (ret []string) {
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			ret = append(ret, p)
+		}
+	}
+	return
+}
+
+// newFileFilter combines the include and exclude arguments with
+// the patterns of the project folder, if any.
+func newFileFilter(include, exclude string, folder *backend.Folder) *fileFilter {
+	ff := &fileFilter{
+		include: splitPatterns(include),
+		exclude: splitPatterns(exclude),
+	}
+	if folder != nil {
+		ff.include = append(ff.include, folder.FileIncludePatterns...)
+		ff.exclude = append(ff.exclude, folder.FileExcludePatterns...)
+		ff.folderExclude = folder.ExcludePatterns
+	}
+	return ff
+}
+
+func matchAny(patterns []string, path string) bool {
+	name := filepath.Base(path)
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (ff *fileFilter) skipDir(path string) bool {
+	return matchAny(ff.exclude, path) || matchAny(ff.folderExclude, path)
+}
+
+func (ff *fileFilter) skipFile(path string) bool {
+	if matchAny(ff.exclude, path) {
+		return true
+	}
+	return len(ff.include) != 0 && !matchAny(ff.include, path)
+}
+
+// walkFolder calls fn with every file under root which
+// passes the filter, until ctx is cancelled.
+func walkFolder(ctx context.Context, root string, ff *fileFilter, fn func(path string)) {
+	filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return nil
+		}
+		if fi.IsDir() {
+			if path != root && ff.skipDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.Mode().IsRegular() && !ff.skipFile(path) {
+			fn(path)
+		}
+		return nil
+	})
+}
+
+// isBinary reports whether data looks like the content of
+// a binary file, that is it has a NUL byte near its start.
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+// grep returns the lines of data, the content of the file at path,
+// matching f or nil if there are none. Unlike in a view the pattern
+// is matched one line at a time, as replace_in_files replaces it. ws
+// is the compiled "word_separators" setting, see isWordBoundary.
+func grep(f *finder, ws *regexp.Regexp, path, data string) *fileResult {
+	r := &fileResult{path: path}
+	for i, l := range strings.Split(data, "\n") {
+		l = strings.TrimSuffix(l, "\r")
+		n := 0
+		for _, loc := range f.re.FindAllStringIndex(l, -1) {
+			if !f.wholeWord || isWordBoundary(l, loc[0], ws) && isWordBoundary(l, loc[1], ws) {
+				n++
+			}
+		}
+		if n != 0 {
+			r.lines = append(r.lines, lineMatch{row: i + 1, text: l})
+			r.matches += n
+		}
+	}
+	if len(r.lines) == 0 {
+		return nil
+	}
+	return r
+}
+
+// wordSeparators compiles the "word_separators" setting of s,
+// the characters View.Classify splits words at.
+func wordSeparators(s *text.Settings) *regexp.Regexp {
+	p := s.String("word_separators", backend.DEFAULT_SEPARATORS)
+	if p == "" {
+		return nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		log.Error("Invalid word_separators %q: %s", p, err)
+		return nil
+	}
+	return re
+}
+
+// isWordBoundary reports whether View.Classify would put the byte
+// offset i of the line s in one of wordBoundaryClasses, ws being the
+// word separators. It lets find in files match whole words in files
+// which aren't open like the finder does in views.
+func isWordBoundary(s string, i int, ws *regexp.Regexp) bool {
+	var a, b string
+	if i > 0 {
+		_, n := utf8.DecodeLastRuneInString(s[:i])
+		a = s[i-n : i]
+	}
+	if i < len(s) {
+		_, n := utf8.DecodeRuneInString(s[i:])
+		b = s[i : i+n]
+	}
+	sep := func(c string) bool {
+		return ws != nil && ws.MatchString(c)
+	}
+	switch {
+	case a == b && sep(a):
+		return false
+	case a == "" || a == "\n" || b == "" || b == "\n":
+		// A line start or end.
+		return true
+	case sep(a) != sep(b):
+		// A punctuation start or end.
+		return true
+	}
+	// A word start or end.
+	return isWordChar(b) && (sep(a) || isSpace(a)) || isWordChar(a) && (sep(b) || isSpace(b))
+}
+
+// isWordChar reports whether c is a word character,
+// as matched by \w in the backend's regular expressions.
+func isWordChar(c string) bool {
+	r, _ := utf8.DecodeRuneInString(c)
+	return r == '_' || unicode.In(r, unicode.L, unicode.M, unicode.Nd, unicode.Pc)
+}
+
+func isSpace(c string) bool {
+	r, _ := utf8.DecodeRuneInString(c)
+	return c != "" && unicode.IsSpace(r)
+}
+
+// showResults makes s the content of the window's results view
+// called name, creating it if needed, and activates the view.
+// lineRe is the view's "result_line_regex" setting.
+func showResults(w *backend.Window, name, lineRe, s string) *backend.View {
+	var v *backend.View
+	for _, v2 := range w.Views() {
+		if v2.Name() == name {
+			v = v2
+			break
+		}
+	}
+	if v == nil {
+		v = w.NewFile()
+		v.SetName(name)
+		v.SetScratch(true)
+		v.Settings().Set("result_file_regex", `^([^ \t].*):$`)
+		v.Settings().Set("result_line_regex", lineRe)
+	}
+	e := v.BeginEdit()
+	v.Replace(e, text.Region{0, v.Size()}, s)
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{0, 0})
+	v.Settings().Erase(limeCmdResult)
+	w.SetActiveView(v)
+	return v
+}
+
+func writeFindResults(w *backend.Window, search string, files int, rs []fileResult) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Searching %d files for %q\n", files, search)
+	matches := 0
+	for _, r := range rs {
+		fmt.Fprintf(&buf, "\n%s:\n", r.path)
+		for _, l := range r.lines {
+			fmt.Fprintf(&buf, "%5d: %s\n", l.row, l.text)
+		}
+		matches += r.matches
+	}
+	fmt.Fprintf(&buf, "\n%d matches across %d files\n", matches, len(rs))
+	showResults(w, findResultsName, `^ +([0-9]+):`, buf.String())
+}
+
+func init() {
+	register([]backend.Command{
+		&FindInFiles{},
+	})
+}
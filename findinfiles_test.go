@@ -0,0 +1,195 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestFindInFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "findinfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.go":         "package a\n\nfunc Foo() {}\n",
+		"b.txt":        "foo\nbar FOO foo\n",
+		"sub/c.go":     "// foo\n",
+		"skip/d.go":    "foo\n",
+		"bin/e.go.bin": "foo\x00bar\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+
+	tests := []struct {
+		args backend.Args
+		exp  string
+	}{
+		{
+			backend.Args{"search_text": "foo", "case_sensitive": true, "exclude": "skip"},
+			fmt.Sprintf("Searching 4 files for \"foo\"\n\n%s:\n    1: foo\n    2: bar FOO foo\n\n%s:\n    1: // foo\n\n3 matches across 2 files\n",
+				p("b.txt"), p("sub/c.go")),
+		},
+		{
+			backend.Args{"search_text": "foo", "case_sensitive": false, "include": "*.go", "exclude": "skip"},
+			fmt.Sprintf("Searching 2 files for \"foo\"\n\n%s:\n    3: func Foo() {}\n\n%s:\n    1: // foo\n\n2 matches across 2 files\n",
+				p("a.go"), p("sub/c.go")),
+		},
+		{
+			backend.Args{"search_text": `^f\w+$`, "regex": true, "case_sensitive": true, "include": "*.txt"},
+			fmt.Sprintf("Searching 1 files for \"^f\\\\w+$\"\n\n%s:\n    1: foo\n\n1 matches across 1 files\n",
+				p("b.txt")),
+		},
+		{
+			backend.Args{"search_text": "oo", "regex": false, "whole_word": true},
+			"Searching 5 files for \"oo\"\n\n0 matches across 0 files\n",
+		},
+		{
+			backend.Args{"search_text": "foo", "case_sensitive": false, "whole_word": true, "include": "*.txt"},
+			fmt.Sprintf("Searching 1 files for \"foo\"\n\n%s:\n    1: foo\n    2: bar FOO foo\n\n3 matches across 1 files\n",
+				p("b.txt")),
+		},
+		{
+			backend.Args{"search_text": "nothing", "regex": false, "whole_word": false},
+			"Searching 5 files for \"nothing\"\n\n0 matches across 0 files\n",
+		},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunWindowCommand(w, "find_in_files", test.args)
+		v := w.ActiveView()
+		if v == nil || v.Name() != findResultsName {
+			t.Fatalf("Test %d: Expected the find results view to be active", i)
+		}
+		if out := v.Substr(text.Region{0, v.Size()}); out != test.exp {
+			t.Errorf("Test %d: Expected\n%s\nbut got\n%s", i, test.exp, out)
+		}
+	}
+	if n := len(w.Views()); n != 1 {
+		t.Errorf("Expected the find results view to be reused, but got %d views", n)
+	}
+}
+
+func TestFindInFilesCancel(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	s := windowSearch(w)
+	ctx, _ := s.startFindInFiles()
+	s.startFindInFiles()
+	if ctx.Err() == nil {
+		t.Error("Expected starting a search to cancel the running one")
+	}
+}
+
+func TestSearchFilesCancelled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "findinfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < 500; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.txt", i)), []byte("foo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+	f, err := newFinder("foo", findOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file count is only read once the walk is done, even if
+	// the workers give up early, which go test -race checks.
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+	if files, _ := searchFiles(ctx, w, f, "", ""); files > 500 {
+		t.Errorf("Expected at most 500 files searched, but got %d", files)
+	}
+}
+
+func TestIsWordBoundary(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	// The boundaries must be where the finder finds them in a view.
+	const input = "id, valid-ID.x_y  (a+b)\n\tfoo.bar::baz é1 --x\n\n$a = [1, 2];"
+	e := v.BeginEdit()
+	v.Insert(e, 0, input)
+	v.EndEdit(e)
+	for _, sep := range []string{backend.DEFAULT_SEPARATORS, "[-.]"} {
+		v.Settings().Set("word_separators", sep)
+		w.Settings().Set("word_separators", sep)
+		ws := wordSeparators(w.Settings())
+		p := 0
+		for _, l := range strings.Split(input, "\n") {
+			for i := range l + " " {
+				if i > len(l) {
+					break
+				}
+				pos := p + utf8.RuneCountInString(l[:i])
+				exp := v.Classify(pos)&wordBoundaryClasses != 0
+				if got := isWordBoundary(l, i, ws); got != exp {
+					t.Errorf("%s: Expected %v at %d of %q, but got %v", sep, exp, i, l, got)
+				}
+			}
+			p += utf8.RuneCountInString(l) + 1
+		}
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		data string
+		exp  bool
+	}{
+		{"", false},
+		{"foo\nbar\n", false},
+		{"foo\x00bar", true},
+		{strings.Repeat("a", 9000) + "\x00", false},
+	}
+	for i, test := range tests {
+		if b := isBinary([]byte(test.data)); b != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, b)
+		}
+	}
+}
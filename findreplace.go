@@ -6,6 +6,11 @@ package commands
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
 	"github.com/limetext/backend"
 	"github.com/limetext/text"
 )
@@ -18,114 +23,570 @@ type (
 	// the selection set.
 	FindUnderExpand struct {
 		backend.DefaultCommand
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+	}
+
+	// FindUnderExpandSkip Command is like FindUnderExpand, except that
+	// the most recently added region is dropped from the selection when
+	// the next occurrence is added, skipping over it.
+	FindUnderExpandSkip struct {
+		backend.DefaultCommand
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+	}
+
+	// SoftUndoFindUnderExpand Command removes the region most recently
+	// added by FindUnderExpand from the selection, and shows the one
+	// added before it.
+	SoftUndoFindUnderExpand struct {
+		backend.DefaultCommand
+	}
+
+	// FindAllUnder Command selects every occurrence of the most
+	// recently added selection, or of the word under it if it's
+	// empty.
+	FindAllUnder struct {
+		backend.DefaultCommand
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
 	}
-	// FindNext command searches for the last search term, starting at
+
+	// FindNext command searches for the window's search term, starting at
 	// the end of the last selection in the buffer, and wrapping around. If
 	// it finds the term, it clears the current selections and selects the
 	// newly-found regions.
 	FindNext struct {
 		backend.DefaultCommand
+		// Whether to search forward or backwards, defaults to true.
+		Forward bool
+		// Whether the search term is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+		// A scope selector such as "comment" or "-string",
+		// only matches starting in a satisfying scope are found.
+		Scope string
+	}
+
+	// FindPrev command is the same as FindNext, except that it
+	// searches backwards from the beginning of the first selection
+	// and wraps around to the end of the buffer.
+	FindPrev struct {
+		backend.DefaultCommand
+		// Whether the search term is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+		// A scope selector such as "comment" or "-string",
+		// only matches starting in a satisfying scope are found.
+		Scope string
 	}
 
-	// ReplaceNext Command searches for the "old" argument text,
+	// ReplaceNext Command searches for the window's search term,
 	// and at the first occurance of the text, replaces it with the
-	// "new" argument text. If there are multiple regions, the find
-	// starts from the max region.
+	// window's replacement text. If there are multiple regions, the
+	// find starts from the max region.
 	ReplaceNext struct {
 		backend.DefaultCommand
+		// Whether to search forward or backwards, defaults to true.
+		// Searching backwards starts from the min region.
+		Forward bool
+		// Whether the search term is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+		// Whether the replacement takes on the casing of the
+		// matched text, e.g. replacing foo with bar turns
+		// FOO into BAR and foo_name into bar_name.
+		PreserveCase bool
 	}
 
+	// FindAll Command selects every occurrence of SearchText
+	// in the buffer.
 	FindAll struct {
 		backend.DefaultCommand
 		SearchText []rune
+		// Whether SearchText is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+		// A scope selector such as "comment" or "-string",
+		// only matches starting in a satisfying scope are found.
+		Scope string
+		// Whether only the non-empty selections are searched
+		// rather than the whole buffer.
+		InSelection bool
 	}
 
+	// ReplaceAll Command replaces every occurrence of SearchText
+	// in the buffer with ReplaceText. In regex mode group references
+	// such as $1, \1 or ${name} in ReplaceText are expanded.
 	ReplaceAll struct {
 		backend.DefaultCommand
 		SearchText  []rune
 		ReplaceText []rune
+		// Whether SearchText is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+		// A scope selector such as "comment" or "-string",
+		// only matches starting in a satisfying scope are found.
+		Scope string
+		// Whether only the non-empty selections are searched
+		// rather than the whole buffer.
+		InSelection bool
+		// Whether the replacement takes on the casing of the
+		// matched text, e.g. replacing foo with bar turns
+		// FOO into BAR and foo_name into bar_name.
+		PreserveCase bool
+	}
+
+	// PatternError is returned by the find and replace commands
+	// when the search text is not a valid regular expression.
+	PatternError struct {
+		Pattern string
+		Err     error
+	}
+
+	// findFlag is a boolean command argument which falls back to
+	// a view setting when it isn't given. When it is given the
+	// setting is updated so the choice persists for later searches.
+	findFlag struct {
+		set, value bool
+	}
+
+	// findOptions are the options shared by the find
+	// and replace commands.
+	findOptions struct {
+		regex, caseSensitive, wholeWord bool
+		// The scope selector matches must start in, if any.
+		scope string
 	}
-)
 
-var (
-	// Remembers the last sequence of runes searched for.
-	lastSearch  []rune
-	replaceText string
+	// finder is a compiled search term.
+	finder struct {
+		re *regexp.Regexp
+		findOptions
+	}
+
+	// findMatch is a single match of a finder in a view's buffer.
+	findMatch struct {
+		text.Region
+		// The searched text and the byte offsets of
+		// the submatches in it, used for expansion.
+		src string
+		loc []int
+	}
 )
 
+// Matches the \1 style group references in a replacement.
+var backrefRe = regexp.MustCompile(`\\(\d+)`)
+
+// The classes of points which don't split a word in two.
+const wordBoundaryClasses = backend.CLASS_WORD_START | backend.CLASS_WORD_END |
+	backend.CLASS_PUNCTUATION_START | backend.CLASS_PUNCTUATION_END |
+	backend.CLASS_LINE_START | backend.CLASS_LINE_END
+
+// Error implements the error interface.
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("Invalid search pattern %q: %s", e.Pattern, e.Err)
+}
+
+// Set implements backend.CustomSet.
+func (f *findFlag) Set(v interface{}) error {
+	switch b := v.(type) {
+	case bool:
+		*f = findFlag{set: true, value: b}
+	case findFlag:
+		*f = b
+	default:
+		return fmt.Errorf("Expected a boolean but got %v", v)
+	}
+	return nil
+}
+
+func (f findFlag) resolve(s *text.Settings, setting string) bool {
+	if f.set {
+		s.Set(setting, f.value)
+		return f.value
+	}
+	return s.Bool(setting, false)
+}
+
+// resolveFindOptions returns the options given by the command
+// arguments, falling back to the view's settings.
+func resolveFindOptions(v *backend.View, regex, caseSensitive, wholeWord findFlag) findOptions {
+	return findOptions{
+		regex:         regex.resolve(v.Settings(), "find_regex"),
+		caseSensitive: caseSensitive.resolve(v.Settings(), "find_case_sensitive"),
+		wholeWord:     wholeWord.resolve(v.Settings(), "find_whole_word"),
+	}
+}
+
+func newFinder(search string, opts findOptions) (*finder, error) {
+	pat := search
+	if !opts.regex {
+		pat = regexp.QuoteMeta(search)
+	}
+	flags := "(?m)"
+	if !opts.caseSensitive {
+		flags = "(?im)"
+	}
+	re, err := regexp.Compile(flags + pat)
+	if err != nil {
+		return nil, &PatternError{Pattern: search, Err: err}
+	}
+	return &finder{re: re, findOptions: opts}, nil
+}
+
+// matches returns every non-overlapping match of the pattern in
+// the buffer in order, whether f accepts it or not. The pattern runs
+// once over the whole buffer so anchors such as ^ and \b see the
+// text around the matches, which are then mapped from byte to rune
+// offsets.
+func (f *finder) matches(v *backend.View) []*findMatch {
+	src := v.Substr(text.Region{0, v.Size()})
+	var ms []*findMatch
+	bytePos, runePos := 0, 0
+	for _, loc := range f.re.FindAllStringSubmatchIndex(src, -1) {
+		runePos += utf8.RuneCountInString(src[bytePos:loc[0]])
+		bytePos = loc[0]
+		r := text.Region{runePos, runePos + utf8.RuneCountInString(src[loc[0]:loc[1]])}
+		ms = append(ms, &findMatch{Region: r, src: src, loc: loc})
+	}
+	return ms
+}
+
+// find returns the first accepted match at or after pos,
+// or nil if there is none.
+func (f *finder) find(v *backend.View, pos int) *findMatch {
+	return f.firstIn(v, f.matches(v), pos)
+}
+
+// firstIn returns the first of the matches ms which is
+// accepted and at or after pos, or nil if there is none.
+func (f *finder) firstIn(v *backend.View, ms []*findMatch, pos int) *findMatch {
+	i := sort.Search(len(ms), func(i int) bool { return ms[i].Begin() >= pos })
+	for ; i < len(ms); i++ {
+		if f.accepts(v, ms[i].Region) {
+			return ms[i]
+		}
+	}
+	return nil
+}
+
+// lastIn returns the last of the matches ms which is accepted
+// and lies before pos, or nil if there is none.
+func (f *finder) lastIn(v *backend.View, ms []*findMatch, pos int) *findMatch {
+	i := sort.Search(len(ms), func(i int) bool { return ms[i].End() > pos || ms[i].Begin() >= pos })
+	for i--; i >= 0; i-- {
+		if f.accepts(v, ms[i].Region) {
+			return ms[i]
+		}
+	}
+	return nil
+}
+
+// accepts reports whether the match r satisfies the whole
+// word and scope options of f.
+func (f *finder) accepts(v *backend.View, r text.Region) bool {
+	if f.wholeWord && !isWholeWord(v, r) {
+		return false
+	}
+	return f.scope == "" || scopeMatches(v.ScopeName(r.Begin()), f.scope)
+}
+
+// isWholeWord reports whether neither end of r splits
+// a word as classified by View.Classify.
+func isWholeWord(v *backend.View, r text.Region) bool {
+	return v.Classify(r.Begin())&wordBoundaryClasses != 0 &&
+		v.Classify(r.End())&wordBoundaryClasses != 0
+}
+
+// each calls fn with every accepted match at or after
+// pos in order, until fn returns false.
+func (f *finder) each(v *backend.View, pos int, fn func(m *findMatch) bool) {
+	for _, m := range f.matches(v) {
+		if m.Begin() >= pos && f.accepts(v, m.Region) && !fn(m) {
+			return
+		}
+	}
+}
+
+// findAll returns every accepted match in the buffer.
+func (f *finder) findAll(v *backend.View) []*findMatch {
+	return f.findAllIn(v, []text.Region{{0, v.Size()}})
+}
+
+// findAllIn returns the accepted matches lying within one
+// of the sorted, non-overlapping regions rs.
+func (f *finder) findAllIn(v *backend.View, rs []text.Region) []*findMatch {
+	var ms []*findMatch
+	i := 0
+	for _, m := range f.matches(v) {
+		for i < len(rs) && rs[i].End() < m.End() {
+			i++
+		}
+		if i == len(rs) {
+			break
+		}
+		if m.Begin() >= rs[i].Begin() && f.accepts(v, m.Region) {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// searchScope returns the regions FindAll and ReplaceAll search,
+// the non-empty selections in order when inSelection is set and
+// otherwise the whole buffer.
+func searchScope(v *backend.View, inSelection bool) []text.Region {
+	if !inSelection {
+		return []text.Region{{0, v.Size()}}
+	}
+	var rs []text.Region
+	for _, r := range v.Sel().Regions() {
+		if !r.Empty() {
+			rs = append(rs, r)
+		}
+	}
+	sort.Sort(regionSorter(rs))
+	return rs
+}
+
+// expand returns the replacement for m. In regex mode the
+// group references in template are expanded.
+func (f *finder) expand(m *findMatch, template string) string {
+	if !f.regex {
+		return template
+	}
+	template = backrefRe.ReplaceAllString(template, "$${$1}")
+	return string(f.re.ExpandString(nil, template, m.src, m.loc))
+}
+
+// replaceString returns s with all the matches of f replaced
+// by template, expanded as by expand.
+func (f *finder) replaceString(s, template string) string {
+	if !f.regex {
+		return f.re.ReplaceAllLiteralString(s, template)
+	}
+	return f.re.ReplaceAllString(s, backrefRe.ReplaceAllString(template, "$${$1}"))
+}
+
+// findUnder makes the text of r the window's search term and
+// returns a finder for it. The text is always searched for literally.
+func findUnder(v *backend.View, r text.Region, caseSensitive, wholeWord findFlag) (*finder, error) {
+	search := v.Substr(r)
+	setLastSearch(v, search)
+	return newFinder(search, findOptions{
+		caseSensitive: caseSensitive.resolve(v.Settings(), "find_case_sensitive"),
+		wholeWord:     wholeWord.resolve(v.Settings(), "find_whole_word"),
+	})
+}
+
+// expandToWords expands the empty regions of the selection to
+// the words they are in, returning false if there were none.
+func expandToWords(v *backend.View) bool {
+	sel := v.Sel()
+	if !sel.HasEmpty() {
+		return false
+	}
+	rs := sel.Regions()
+	for i, r := range rs {
+		if r2 := v.Word(r.A); r2.Size() > r.Size() {
+			rs[i] = r2
+		}
+	}
+	sel.Clear()
+	sel.AddAll(rs)
+	return true
+}
+
 // Run executes the FindUnderExpand command.
 func (c *FindUnderExpand) Run(v *backend.View, e *backend.Edit) error {
 	sel := v.Sel()
+	if expandToWords(v) {
+		rs := sel.Regions()
+		_, err := findUnder(v, rs[len(rs)-1], c.CaseSensitive, c.WholeWord)
+		return err
+	}
 	rs := sel.Regions()
+	last := rs[len(rs)-1]
+	f, err := findUnder(v, last, c.CaseSensitive, c.WholeWord)
+	if err != nil {
+		return err
+	}
+	if m := f.find(v, last.End()); m != nil {
+		sel.Add(m.Region)
+	}
+	return nil
+}
 
-	if sel.HasEmpty() {
-		for i, r := range rs {
-			if r2 := v.Word(r.A); r2.Size() > r.Size() {
-				rs[i] = r2
-			}
-		}
+// Run executes the FindUnderExpandSkip command.
+func (c *FindUnderExpandSkip) Run(v *backend.View, e *backend.Edit) error {
+	sel := v.Sel()
+	if expandToWords(v) {
+		rs := sel.Regions()
+		_, err := findUnder(v, rs[len(rs)-1], c.CaseSensitive, c.WholeWord)
+		return err
+	}
+	rs := sel.Regions()
+	last := rs[len(rs)-1]
+	f, err := findUnder(v, last, c.CaseSensitive, c.WholeWord)
+	if err != nil {
+		return err
+	}
+	if m := f.find(v, last.End()); m != nil {
 		sel.Clear()
-		sel.AddAll(rs)
-		lastSearch = v.SubstrR(rs[len(rs)-1])
+		sel.AddAll(rs[:len(rs)-1])
+		sel.Add(m.Region)
+		backend.GetEditor().Frontend().Show(v, m.Region)
+	}
+	return nil
+}
+
+// Run executes the SoftUndoFindUnderExpand command.
+func (c *SoftUndoFindUnderExpand) Run(v *backend.View, e *backend.Edit) error {
+	sel := v.Sel()
+	rs := sel.Regions()
+	if len(rs) < 2 {
 		return nil
 	}
+	sel.Clear()
+	sel.AddAll(rs[:len(rs)-1])
+	backend.GetEditor().Frontend().Show(v, rs[len(rs)-2])
+	return nil
+}
+
+// Run executes the FindAllUnder command.
+func (c *FindAllUnder) Run(v *backend.View, e *backend.Edit) error {
+	sel := v.Sel()
+	expandToWords(v)
+	rs := sel.Regions()
 	last := rs[len(rs)-1]
-	lastSearch = v.SubstrR(last)
-	r := v.Find(string(lastSearch), last.End(), backend.IGNORECASE|backend.LITERAL)
-	if r.A != -1 {
-		sel.Add(r)
+	if last.Empty() {
+		return nil
+	}
+	f, err := findUnder(v, last, c.CaseSensitive, c.WholeWord)
+	if err != nil {
+		return err
+	}
+	sel.Clear()
+	for _, m := range f.findAll(v) {
+		sel.Add(m.Region)
 	}
 	return nil
 }
 
-func nextSelection(v *backend.View, search string) (text.Region, error) {
+// setLastSearch remembers s as the last search term, searched
+// for literally even when searches use regular expressions.
+func setLastSearch(v *backend.View, s string) {
+	searchFor(v).setLiteralSearch(s, searchHistorySize(v.Window()))
+}
+
+func nextSelection(v *backend.View, f *finder, forward bool) (*findMatch, error) {
 	sel := v.Sel()
 	rs := sel.Regions()
-	last := 0
 	wrap := v.Settings().Bool("find_wrap")
 
-	// Regions are not sorted, so finding the last one requires a search.
-	for _, r := range rs {
-		last = text.Max(last, r.End())
-	}
+	// The matches of one pass serve both the search
+	// and its wrapping around.
+	ms := f.matches(v)
+	var m *findMatch
+	if forward {
+		last := 0
+		// Regions are not sorted, so finding the last one requires a search.
+		for _, r := range rs {
+			last = text.Max(last, r.End())
+		}
+
+		// Start the search right after the last selection.
+		m = f.firstIn(v, ms, last)
+		// If not found yet and find_wrap setting is true, search
+		// from the start of the buffer to our original starting point.
+		if m == nil && wrap {
+			m = f.firstIn(v, ms, 0)
+		}
+	} else {
+		first := v.Size()
+		for _, r := range rs {
+			first = text.Min(first, r.Begin())
+		}
 
-	// Start the search right after the last selection.
-	start := last
-	r := v.Find(search, start, backend.IGNORECASE|backend.LITERAL)
-	// If not found yet and find_wrap setting is true, search
-	// from the start of the buffer to our original starting point.
-	if r.A == -1 && wrap {
-		r = v.Find(search, 0, backend.IGNORECASE|backend.LITERAL)
+		// Search backwards from right before the first selection,
+		// wrapping around to the end of the buffer.
+		m = f.lastIn(v, ms, first)
+		if m == nil && wrap {
+			m = f.lastIn(v, ms, v.Size())
+		}
 	}
 	// If we found our string, select it.
-	if r.A != -1 {
-		return r, nil
+	if m != nil {
+		return m, nil
 	}
-	return text.Region{-1, -1}, errors.New("Selection not Found")
+	return nil, errors.New("Selection not Found")
 }
 
+// Run executes the FindAll command.
 func (c *FindAll) Run(v *backend.View, e *backend.Edit) error {
 	if len(c.SearchText) == 0 {
 		return nil
 	}
-	/* Original state of find_wrap is stored and then is made false so that nextSection doesn't go into an infinate loop.
-	   Later in the end, it is returned to it's original state.
-	*/
-	wrap := v.Settings().Bool("find_wrap")
-	v.Settings().Set("find_wrap", false)
-	search := string(c.SearchText)
+	opts := resolveFindOptions(v, c.Regex, c.CaseSensitive, c.WholeWord)
+	opts.scope = c.Scope
+	f, err := newFinder(string(c.SearchText), opts)
+	if err != nil {
+		return err
+	}
+	setSearch(v, string(c.SearchText))
+	ms := f.findAllIn(v, searchScope(v, c.InSelection))
+	if c.InSelection && len(ms) == 0 {
+		return nil
+	}
 	sel := v.Sel()
 	sel.Clear()
-	for {
-		selection, err := nextSelection(v, search)
-		if err != nil {
-			break
-		}
-		sel.Add(selection)
+	for _, m := range ms {
+		sel.Add(m.Region)
 	}
-	v.Settings().Set("find_wrap", wrap)
 	return nil
-
 }
 
 // Run executes the FindNext command.
@@ -137,68 +598,159 @@ func (c *FindNext) Run(v *backend.View, e *backend.Edit) error {
 			  search immediately after that.
 			- If the search term is found, clear any existing
 			  selections, and select the newly-found region.
-			- Right now this is doing a case-sensitive search. In ST3
-			  that's a setting.
+			- The search is case sensitive when the case_sensitive
+			  argument or the find_case_sensitive setting is set.
 	*/
+	opts := resolveFindOptions(v, c.Regex, c.CaseSensitive, c.WholeWord)
+	opts.scope = c.Scope
+	return findNext(v, opts, c.Forward)
+}
+
+// Default returns the default values of the FindNext arguments.
+func (c *FindNext) Default(key string) interface{} {
+	if key == "forward" {
+		return true
+	}
+	return nil
+}
+
+// Run executes the FindPrev command.
+func (c *FindPrev) Run(v *backend.View, e *backend.Edit) error {
+	opts := resolveFindOptions(v, c.Regex, c.CaseSensitive, c.WholeWord)
+	opts.scope = c.Scope
+	return findNext(v, opts, false)
+}
 
+// findNext selects the next occurrence of the search term
+// of v's window.
+func findNext(v *backend.View, opts findOptions, forward bool) error {
+	search, literal := searchFor(v).searchTerm()
 	// If there is no last search term, nothing to do here.
-	if len(lastSearch) == 0 {
+	if search == "" {
 		return nil
 	}
-	newr, err := nextSelection(v, string(lastSearch))
+	if literal {
+		opts.regex = false
+	}
+	f, err := newFinder(search, opts)
+	if err != nil {
+		return err
+	}
+	m, err := nextSelection(v, f, forward)
 	if err != nil {
 		return err
 	}
+	pushJump(v)
 	sel := v.Sel()
 	sel.Clear()
-	sel.Add(newr)
+	sel.Add(m.Region)
 	return nil
 }
 
+// Run executes the ReplaceAll command.
 func (c *ReplaceAll) Run(v *backend.View, e *backend.Edit) error {
 	if len(c.SearchText) == 0 {
 		return nil
 	}
-	search := string(c.SearchText)
+	opts := resolveFindOptions(v, c.Regex, c.CaseSensitive, c.WholeWord)
+	opts.scope = c.Scope
+	f, err := newFinder(string(c.SearchText), opts)
+	if err != nil {
+		return err
+	}
 	replace := string(c.ReplaceText)
+	setSearch(v, string(c.SearchText))
+	setReplace(v, replace)
+	scope := searchScope(v, c.InSelection)
 	sel := v.Sel()
+	orig := sel.Regions()
 	sel.Clear()
-	/* Original state of find_wrap is stored and then is made false so that nextSection doesn't go into an infinate loop.
-	   Later in the end, it is returned to it's original state.
-	*/
-	wrap := v.Settings().Bool("find_wrap")
-	v.Settings().Set("find_wrap", false)
-	for {
-		selection, err := nextSelection(v, string(search))
-		if err != nil {
-			break
+
+	// Expansions are done against the original buffer, so all
+	// the replacements are computed before changing anything.
+	ms := f.findAllIn(v, scope)
+	repls := make([]string, len(ms))
+	for i, m := range ms {
+		repls[i] = f.expand(m, replace)
+		if c.PreserveCase {
+			repls[i] = matchCase(v.Substr(m.Region), repls[i])
+		}
+	}
+
+	offset := 0
+	var last text.Region
+	for i, m := range ms {
+		r := text.Region{m.A + offset, m.B + offset}
+		v.Replace(e, r, repls[i])
+		l := utf8.RuneCountInString(repls[i])
+		offset += l - r.Size()
+		last = text.Region{r.Begin(), r.Begin() + l}
+	}
+	if c.InSelection {
+		// Keep the searched regions selected, moved and resized
+		// by the replacements.
+		shift := func(p int) int {
+			for i, m := range ms {
+				if m.End() <= p {
+					p += utf8.RuneCountInString(repls[i]) - m.Size()
+				}
+			}
+			return p
 		}
-		v.Erase(e, selection)
-		v.Insert(e, selection.Begin(), replace)
-		sel := v.Sel()
 		sel.Clear()
-		sel.Add(text.Region{selection.Begin(), selection.Begin() + len(replace)})
+		for _, r := range orig {
+			sel.Add(text.Region{shift(r.A), shift(r.B)})
+		}
+	} else if len(ms) > 0 {
+		sel.Add(last)
 	}
-	v.Settings().Set("find_wrap", wrap)
 	return nil
 }
 
 // Run executes the ReplaceNext command.
 func (c *ReplaceNext) Run(v *backend.View, e *backend.Edit) error {
+	s := searchFor(v)
+	search, literal := s.searchTerm()
+	if search == "" {
+		return nil
+	}
+	opts := resolveFindOptions(v, c.Regex, c.CaseSensitive, c.WholeWord)
+	if literal {
+		opts.regex = false
+	}
+	f, err := newFinder(search, opts)
+	if err != nil {
+		return err
+	}
 	// use selection function from find.go to get the next region
-	selection, err := nextSelection(v, string(lastSearch))
+	m, err := nextSelection(v, f, c.Forward)
 	if err != nil {
 		return err
 	}
-	v.Erase(e, selection)
-	v.Insert(e, selection.Begin(), replaceText)
+	repl := f.expand(m, s.replaceText())
+	if c.PreserveCase {
+		repl = matchCase(v.Substr(m.Region), repl)
+	}
+	v.Replace(e, m.Region, repl)
+	return nil
+}
+
+// Default returns the default values of the ReplaceNext arguments.
+func (c *ReplaceNext) Default(key string) interface{} {
+	if key == "forward" {
+		return true
+	}
 	return nil
 }
 
 func init() {
 	register([]backend.Command{
 		&FindUnderExpand{},
+		&FindUnderExpandSkip{},
+		&SoftUndoFindUnderExpand{},
+		&FindAllUnder{},
 		&FindNext{},
+		&FindPrev{},
 		&ReplaceNext{},
 		&ReplaceAll{},
 		&FindAll{},
@@ -6,6 +6,7 @@ package commands
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/limetext/backend"
@@ -72,6 +73,72 @@ func TestFindUnderExpand(t *testing.T) {
 	runFindTest(tests, t, "find_under_expand")
 }
 
+func TestFindUnderExpandVariants(t *testing.T) {
+	ed := backend.GetEditor()
+	var fe front
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc abd abc ABC abc\n")
+	v.EndEdit(e)
+	v.Settings().Set("find_case_sensitive", true)
+	v.Settings().Set("find_whole_word", false)
+
+	tests := []struct {
+		in       []text.Region
+		commands []string
+		exp      []text.Region
+	}{
+		{
+			[]text.Region{{0, 0}},
+			[]string{"find_under_expand", "find_under_expand", "find_under_expand_skip"},
+			[]text.Region{{0, 3}, {16, 19}},
+		},
+		{
+			[]text.Region{{0, 3}},
+			[]string{"find_under_expand_skip"},
+			[]text.Region{{8, 11}},
+		},
+		{
+			[]text.Region{{0, 0}},
+			[]string{"find_under_expand", "find_under_expand", "find_under_expand", "soft_undo_find_under_expand"},
+			[]text.Region{{0, 3}, {8, 11}},
+		},
+		{
+			[]text.Region{{0, 3}},
+			[]string{"soft_undo_find_under_expand"},
+			[]text.Region{{0, 3}},
+		},
+		{
+			[]text.Region{{9, 9}},
+			[]string{"find_all_under"},
+			[]text.Region{{0, 3}, {8, 11}, {16, 19}},
+		},
+		{
+			[]text.Region{{4, 6}},
+			[]string{"find_all_under"},
+			[]text.Region{{0, 2}, {4, 6}, {8, 10}, {16, 18}},
+		},
+	}
+	for i, test := range tests {
+		v.Sel().Clear()
+		v.Sel().AddAll(test.in)
+		for _, cmd := range test.commands {
+			ed.CommandHandler().RunTextCommand(v, cmd, nil)
+		}
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.exp) {
+			t.Errorf("Test %d: Expected %s, but got %s", i, test.exp, sr)
+		}
+	}
+}
+
 func TestFindNext(t *testing.T) {
 	tests := []findTest{
 		{
@@ -99,6 +166,86 @@ func TestFindNext(t *testing.T) {
 	runFindTest(tests, t, "find_under_expand", "find_next")
 }
 
+func TestFindPrev(t *testing.T) {
+	tests := []findTest{
+		{
+			"Hello World!\nTest123123\nAbrakadabra\n",
+			[]text.Region{{20, 23}},
+			[]text.Region{{17, 20}},
+			true,
+		},
+		// test find_wrap setting true
+		{
+			"Hello World!\nTest123123\nAbrakadabra\n",
+			[]text.Region{{17, 20}},
+			[]text.Region{{20, 23}},
+			true,
+		},
+		// test find_wrap setting false
+		{
+			"Hello World!\nTest123123\nAbrakadabra\n",
+			[]text.Region{{17, 20}},
+			[]text.Region{{17, 20}, {20, 23}},
+			false,
+		},
+		{
+			"abc abc abc\n",
+			[]text.Region{{8, 8}},
+			[]text.Region{{4, 7}},
+			true,
+		},
+	}
+
+	runFindTest(tests, t, "find_under_expand", "find_prev")
+}
+
+func TestFindNextBackward(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "ab ab ab")
+	v.EndEdit(e)
+	v.Settings().Set("find_wrap", true)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{4, 4})
+
+	setSearch(v, "ab")
+	exp := [][]text.Region{{{0, 2}}, {{6, 8}}, {{3, 5}}}
+	for i, ex := range exp {
+		ed.CommandHandler().RunTextCommand(v, "find_next", backend.Args{"forward": false})
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, ex) {
+			t.Errorf("Test %d: Expected %s, but got %s", i, ex, sr)
+		}
+	}
+
+	setReplace(v, "X")
+	ed.CommandHandler().RunTextCommand(v, "replace_next", backend.Args{"forward": false})
+	if exp, out := "X ab ab", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+
+	// Matches rejected by whole_word are skipped on the way back.
+	e = v.BeginEdit()
+	v.Replace(e, text.Region{0, v.Size()}, "ab xab xab, ab xab")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{13, 13})
+	exp = [][]text.Region{{{0, 2}}, {{12, 14}}}
+	for i, ex := range exp {
+		ed.CommandHandler().RunTextCommand(v, "find_prev", backend.Args{"whole_word": true})
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, ex) {
+			t.Errorf("Whole word test %d: Expected %s, but got %s", i, ex, sr)
+		}
+	}
+}
+
 type replaceAllTest struct {
 	find    string
 	replace string
@@ -250,7 +397,7 @@ func runReplaceTest(tests []replaceTest, t *testing.T, commands ...string) {
 
 		v.Settings().Set("find_wrap", test.fw)
 
-		replaceText = "f"
+		setReplace(v, "f")
 		for _, command := range commands {
 			ed.CommandHandler().RunTextCommand(v, command, nil)
 		}
@@ -313,3 +460,580 @@ func TestReplaceNext(t *testing.T) {
 
 	runReplaceTest(tests, t, "find_under_expand", "replace_next")
 }
+
+func TestFindAllRegex(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []struct {
+		find string
+		in   string
+		exp  []text.Region
+	}{
+		{
+			`a\w+`,
+			"abc cde dce axe",
+			[]text.Region{{0, 3}, {12, 15}},
+		},
+		{
+			`^\d`,
+			"1a\nb2\n3c",
+			[]text.Region{{0, 1}, {6, 7}},
+		},
+		{
+			"x*",
+			"ab",
+			[]text.Region{{0, 0}, {1, 1}, {2, 2}},
+		},
+		{
+			"é.",
+			"aéb éc",
+			[]text.Region{{1, 3}, {4, 6}},
+		},
+	}
+
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, test.in)
+		v.EndEdit(e)
+		v.Sel().Clear()
+
+		args := backend.Args{"search_text": []rune(test.find), "regex": true}
+		if err := ed.CommandHandler().RunTextCommand(v, "find_all", args); err != nil {
+			t.Errorf("Test %d: Unexpected error: %s", i, err)
+		}
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.exp) {
+			t.Errorf("Test %d: Expected %s, but got %s", i, test.exp, sr)
+		}
+	}
+}
+
+func TestFindRegexAnchors(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	v.Settings().Set("find_wrap", true)
+
+	tests := []struct {
+		cmd    string
+		find   string
+		in     string
+		sel    []text.Region
+		exp    string
+		expSel []text.Region
+	}{
+		{"find_all", `^\d`, "123\n45", nil, "123\n45", []text.Region{{0, 1}, {4, 5}}},
+		{"find_all", `\Bb`, "ab ab bc", nil, "ab ab bc", []text.Region{{1, 2}, {4, 5}}},
+		{"find_all", `c$`, "abc abc", []text.Region{{0, 3}}, "abc abc", []text.Region{{0, 3}}},
+		{"replace_all", `^a`, "aaa", nil, "baa", []text.Region{{0, 1}}},
+		{"find_next", `\bb`, "ab ab bc", []text.Region{{1, 1}}, "ab ab bc", []text.Region{{6, 7}}},
+		{"find_next", `^ab`, "ab ab\nab", []text.Region{{3, 3}}, "ab ab\nab", []text.Region{{6, 8}}},
+		{"find_prev", `b$`, "ab ab\nab", []text.Region{{7, 7}}, "ab ab\nab", []text.Region{{4, 5}}},
+	}
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, test.in)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		setSearch(v, test.find)
+
+		args := backend.Args{"regex": true}
+		switch test.cmd {
+		case "find_all":
+			args["search_text"] = test.find
+			args["in_selection"] = test.sel != nil
+		case "replace_all":
+			args["search_text"] = test.find
+			args["replace_text"] = "b"
+		}
+		if err := ed.CommandHandler().RunTextCommand(v, test.cmd, args); err != nil {
+			t.Errorf("Test %d: Unexpected error: %s", i, err)
+		}
+		if out := v.Substr(text.Region{0, v.Size()}); out != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, out)
+		}
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.expSel) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.expSel, sr)
+		}
+	}
+}
+
+func TestReplaceAllRegex(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []replaceAllTest{
+		{
+			`(\w+)=(\w+)`,
+			"$2=$1",
+			"a=b, cd=ef",
+			"b=a, ef=cd",
+		},
+		{
+			`(\w+)=(\w+)`,
+			`\2:\1`,
+			"a=b, cd=ef",
+			"b:a, ef:cd",
+		},
+		{
+			`(?P<key>\w+)=\w+`,
+			"${key}",
+			"a=b, cd=ef",
+			"a, cd",
+		},
+		{
+			`\s+`,
+			" ",
+			"a  b\t\tc",
+			"a b c",
+		},
+		{
+			`é(.)`,
+			"<$1>",
+			"aéb éc",
+			"a<b> <c>",
+		},
+	}
+
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, test.in)
+		v.EndEdit(e)
+		v.Sel().Clear()
+
+		args := backend.Args{"search_text": []rune(test.find), "replace_text": []rune(test.replace), "regex": true}
+		if err := ed.CommandHandler().RunTextCommand(v, "replace_all", args); err != nil {
+			t.Errorf("Test %d: Unexpected error: %s", i, err)
+		}
+		if out := v.Substr(text.Region{0, v.Size()}); out != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, out)
+		}
+	}
+}
+
+func TestFindRegexSetting(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "a.c abc")
+	v.EndEdit(e)
+
+	ed.CommandHandler().RunTextCommand(v, "find_all", backend.Args{"search_text": []rune("a.c"), "regex": true})
+	if !v.Settings().Bool("find_regex") {
+		t.Error("Expected the regex argument to be persisted in find_regex")
+	}
+
+	// Without the argument the persisted setting is used.
+	ed.CommandHandler().RunTextCommand(v, "find_all", backend.Args{"search_text": []rune("a.c")})
+	if exp, sr := []text.Region{{0, 3}, {4, 7}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %s, but got %s", exp, sr)
+	}
+
+	ed.CommandHandler().RunTextCommand(v, "find_all", backend.Args{"search_text": []rune("a.c"), "regex": false})
+	if exp, sr := []text.Region{{0, 3}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %s, but got %s", exp, sr)
+	}
+}
+
+func TestFindUnderRegexSetting(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	v.Settings().Set("find_regex", true)
+	v.Settings().Set("find_wrap", true)
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "a.b axb a.b")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{0, 3})
+	ed.CommandHandler().RunTextCommand(v, "find_under_expand", nil)
+	if exp, sr := []text.Region{{0, 3}, {8, 11}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %s, but got %s", exp, sr)
+	}
+	// The term is kept as it is, but searched for literally.
+	if s := searchFor(v).searchText(); s != "a.b" {
+		t.Errorf("Expected the search term %q, but got %q", "a.b", s)
+	}
+	for _, args := range []backend.Args{nil, {"regex": false}, {"regex": true}} {
+		v.Sel().Clear()
+		v.Sel().Add(text.Region{3, 3})
+		ed.CommandHandler().RunTextCommand(v, "find_next", args)
+		if exp, sr := []text.Region{{8, 11}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+			t.Errorf("%v: Expected %s, but got %s", args, exp, sr)
+		}
+	}
+
+	// A term set otherwise is a regular expression again.
+	ed.CommandHandler().RunWindowCommand(w, "set_search_text", backend.Args{"text": "a.b"})
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{3, 3})
+	ed.CommandHandler().RunTextCommand(v, "find_next", nil)
+	if exp, sr := []text.Region{{4, 7}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %s, but got %s", exp, sr)
+	}
+}
+
+func TestReplaceNextRegex(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "x1 y22 z333")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{2, 2})
+	v.Settings().Set("find_wrap", true)
+
+	setSearch(v, `([a-z])(\d+)`)
+	setReplace(v, "$2$1")
+	ed.CommandHandler().RunTextCommand(v, "replace_next", backend.Args{"regex": true})
+	if exp, out := "x1 22y z333", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+}
+
+func TestFindInvalidPattern(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc (abc")
+	v.EndEdit(e)
+
+	for _, cmd := range []string{"find_all", "replace_all"} {
+		err := ed.CommandHandler().RunTextCommand(v, cmd, backend.Args{"search_text": []rune("(abc"), "regex": true})
+		if _, ok := err.(*PatternError); !ok {
+			t.Errorf("%s: Expected a *PatternError, but got %#v", cmd, err)
+		}
+	}
+
+	// The same text is fine as a literal search.
+	err := ed.CommandHandler().RunTextCommand(v, "find_all", backend.Args{"search_text": []rune("(abc"), "regex": false})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if exp, sr := []text.Region{{4, 8}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %s, but got %s", exp, sr)
+	}
+}
+
+func TestFindOptions(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []struct {
+		cmd  string
+		in   []text.Region
+		args backend.Args
+		exp  []text.Region
+	}{
+		{
+			"find_all",
+			nil,
+			backend.Args{"search_text": []rune("id")},
+			[]text.Region{{0, 2}, {4, 6}, {9, 11}, {18, 20}, {21, 23}},
+		},
+		{
+			"find_all",
+			nil,
+			backend.Args{"search_text": []rune("id"), "case_sensitive": true},
+			[]text.Region{{0, 2}, {9, 11}, {18, 20}},
+		},
+		{
+			"find_all",
+			nil,
+			backend.Args{"search_text": []rune("id"), "whole_word": true},
+			[]text.Region{{0, 2}, {4, 6}, {21, 23}},
+		},
+		{
+			"find_all",
+			nil,
+			backend.Args{"search_text": []rune("id"), "case_sensitive": true, "whole_word": true},
+			[]text.Region{{0, 2}},
+		},
+		{
+			"find_all",
+			nil,
+			backend.Args{"search_text": []rune(`i\w`), "regex": true, "whole_word": true},
+			[]text.Region{{0, 2}, {4, 6}, {21, 23}},
+		},
+		{
+			"find_under_expand",
+			[]text.Region{{0, 2}},
+			backend.Args{"case_sensitive": true, "whole_word": true},
+			[]text.Region{{0, 2}},
+		},
+		{
+			"find_under_expand",
+			[]text.Region{{0, 2}},
+			backend.Args{"whole_word": true},
+			[]text.Region{{0, 2}, {4, 6}},
+		},
+		{
+			"find_under_expand",
+			[]text.Region{{0, 2}},
+			backend.Args{"case_sensitive": false, "whole_word": false},
+			[]text.Region{{0, 2}, {4, 6}},
+		},
+	}
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "id, ID, width, valid-ID.")
+	v.EndEdit(e)
+
+	for i, test := range tests {
+		v.Settings().Set("find_case_sensitive", false)
+		v.Settings().Set("find_whole_word", false)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.in)
+		ed.CommandHandler().RunTextCommand(v, test.cmd, test.args)
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.exp) {
+			t.Errorf("Test %d: Expected %s, but got %s", i, test.exp, sr)
+		}
+	}
+}
+
+func TestFindWholeWordRejected(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	// Every match but the last splits a word.
+	e := v.BeginEdit()
+	v.Insert(e, 0, strings.Repeat("xid ", 2000)+"id")
+	v.EndEdit(e)
+	exp := []text.Region{{8000, 8002}}
+
+	ed.CommandHandler().RunTextCommand(v, "find_all", backend.Args{"search_text": "id", "whole_word": true})
+	if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("find_all: Expected %s, but got %s", exp, sr)
+	}
+
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{0, 0})
+	v.Settings().Set("find_wrap", true)
+	setSearch(v, "id")
+	ed.CommandHandler().RunTextCommand(v, "find_next", backend.Args{"whole_word": true})
+	if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("find_next: Expected %s, but got %s", exp, sr)
+	}
+}
+
+func TestReplaceAllOptions(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []struct {
+		settings map[string]bool
+		exp      string
+	}{
+		{
+			map[string]bool{},
+			"key, key, wkeyth, valkey",
+		},
+		{
+			map[string]bool{"find_case_sensitive": true},
+			"key, ID, wkeyth, valkey",
+		},
+		{
+			map[string]bool{"find_whole_word": true},
+			"key, key, width, valid",
+		},
+		{
+			map[string]bool{"find_case_sensitive": true, "find_whole_word": true},
+			"key, ID, width, valid",
+		},
+	}
+
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, "id, ID, width, valid")
+		v.EndEdit(e)
+		v.Settings().Set("find_case_sensitive", false)
+		v.Settings().Set("find_whole_word", false)
+		for k, b := range test.settings {
+			v.Settings().Set(k, b)
+		}
+
+		args := backend.Args{"search_text": []rune("id"), "replace_text": []rune("key")}
+		ed.CommandHandler().RunTextCommand(v, "replace_all", args)
+		if out := v.Substr(text.Region{0, v.Size()}); out != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, out)
+		}
+	}
+}
+
+func TestReplacePreserveCase(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "fooBar FooBar FOO_BAR foo_bar foobar")
+	v.EndEdit(e)
+	v.Settings().Set("find_case_sensitive", false)
+	v.Settings().Set("find_wrap", true)
+
+	args := backend.Args{"search_text": "foo_?bar", "replace_text": "baz_qux", "regex": true, "preserve_case": true}
+	ed.CommandHandler().RunTextCommand(v, "replace_all", args)
+	if exp, out := "bazQux BazQux BAZ_QUX baz_qux baz_qux", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+
+	setSearch(v, "bazqux")
+	setReplace(v, "fooBar")
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{7, 7})
+	ed.CommandHandler().RunTextCommand(v, "replace_next", backend.Args{"regex": false, "preserve_case": true})
+	if exp, out := "bazQux FooBar BAZ_QUX baz_qux baz_qux", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+}
+
+func TestFindReplaceInSelection(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	v.Settings().Set("find_case_sensitive", true)
+	v.Settings().Set("find_whole_word", false)
+	v.Settings().Set("find_regex", false)
+
+	const input = "x = x + 1\nfunc f(x) { return x }\nx--"
+	tests := []struct {
+		cmd     string
+		in      []text.Region
+		exp     string
+		expSel  []text.Region
+		replace string
+	}{
+		{
+			"find_all",
+			[]text.Region{{10, 33}},
+			input,
+			[]text.Region{{17, 18}, {29, 30}},
+			"",
+		},
+		{
+			"replace_all",
+			[]text.Region{{10, 33}},
+			"x = x + 1\nfunc f(val) { return val }\nx--",
+			[]text.Region{{10, 37}},
+			"val",
+		},
+		{
+			"replace_all",
+			[]text.Region{{36, 33}, {0, 5}, {20, 20}},
+			"y = y + 1\nfunc f(x) { return x }\ny--",
+			[]text.Region{{36, 33}, {0, 5}, {20, 20}},
+			"y",
+		},
+		{
+			"replace_all",
+			[]text.Region{{3, 3}},
+			input,
+			[]text.Region{{3, 3}},
+			"y",
+		},
+		{
+			"replace_all",
+			[]text.Region{{0, 5}, {20, 20}},
+			"xx = xx + 1\nfunc f(x) { return x }\nx--",
+			[]text.Region{{0, 7}, {22, 22}},
+			"xx",
+		},
+	}
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, input)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.in)
+
+		args := backend.Args{"search_text": "x", "in_selection": true}
+		if test.cmd == "replace_all" {
+			args["replace_text"] = test.replace
+		}
+		ed.CommandHandler().RunTextCommand(v, test.cmd, args)
+		if out := v.Substr(text.Region{0, v.Size()}); out != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, out)
+		}
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.expSel) {
+			t.Errorf("Test %d: Expected selection %v, but got %v", i, test.expSel, sr)
+		}
+	}
+}
@@ -11,6 +11,6 @@ require (
 	github.com/limetext/sublime v0.0.0-20171223152837-94683378b34b // indirect
 	github.com/limetext/text v0.0.0-20190715170947-99815b127d37
 	github.com/limetext/util v0.0.0-20160325174435-20e1a4a3505f
-	github.com/quarnster/parser v0.0.0-20150905092627-8991807ce6d3 // indirect
+	github.com/quarnster/parser v0.0.0-20150905092627-8991807ce6d3
 	github.com/rjeczalik/notify v0.9.2 // indirect
 )
@@ -0,0 +1,122 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+type (
+	// GotoLine command moves the cursor to the given line and
+	// column and scrolls it to the centre of the view.
+	GotoLine struct {
+		backend.DefaultCommand
+		// The 1-based line, negative values count from
+		// the last line.
+		Line int
+		// The 1-based column, negative values count from
+		// the end of the line. The line start if 0.
+		Column int
+	}
+
+	// GotoLinePrompt command is GotoLine taking the position as
+	// typed in a prompt, ":123" for a line or ":123:7" for a line
+	// and column.
+	GotoLinePrompt struct {
+		backend.DefaultCommand
+		Text string
+	}
+)
+
+// Run executes the GotoLine command.
+func (c *GotoLine) Run(v *backend.View, e *backend.Edit) error {
+	gotoLine(v, c.Line, c.Column)
+	return nil
+}
+
+// Run executes the GotoLinePrompt command.
+func (c *GotoLinePrompt) Run(v *backend.View, e *backend.Edit) error {
+	line, col, err := parseGotoLine(c.Text)
+	if err != nil {
+		return err
+	}
+	gotoLine(v, line, col)
+	return nil
+}
+
+// parseGotoLine parses ":line" or ":line:column",
+// the leading colon being optional.
+func parseGotoLine(s string) (line, col int, err error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), ":"), ":")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("goto_line_prompt: Invalid position: %q", s)
+	}
+	if line, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("goto_line_prompt: Invalid line: %q", s)
+	}
+	if len(parts) == 2 {
+		if col, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, fmt.Errorf("goto_line_prompt: Invalid column: %q", s)
+		}
+	}
+	return line, col, nil
+}
+
+// gotoLine places a single cursor at the 1-based line and column,
+// both clamped into the buffer, and centres it in the view.
+func gotoLine(v *backend.View, line, col int) {
+	last := lastRow(v)
+	row := line - 1
+	if line < 0 {
+		row = last + line + 1
+	}
+	row = text.Clamp(0, last, row)
+
+	l := v.Line(v.TextPoint(row, 0))
+	p := l.Begin() + col - 1
+	if col < 0 {
+		p = l.End() + col + 1
+	}
+	p = text.Clamp(l.Begin(), l.End(), p)
+
+	pushJump(v)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{p, p})
+	showCentred(v, row)
+}
+
+// showCentred scrolls the view so row is in the
+// middle of the visible region.
+func showCentred(v *backend.View, row int) {
+	fe := backend.GetEditor().Frontend()
+	vr := fe.VisibleRegion(v)
+	r1, _ := v.RowCol(vr.Begin())
+	r2, _ := v.RowCol(vr.End())
+	h := r2 - r1
+
+	top := row - h/2
+	if top < 0 {
+		top = 0
+	}
+	bottom := top + h
+	if last := lastRow(v); bottom > last {
+		bottom = last
+	}
+	a := v.TextPoint(top, 0)
+	b := v.Line(v.TextPoint(bottom, 0)).End()
+	fe.Show(v, text.Region{a, b})
+}
+
+func init() {
+	register([]backend.Command{
+		&GotoLine{},
+		&GotoLinePrompt{},
+	})
+}
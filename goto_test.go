@@ -0,0 +1,99 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestGotoLine(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc\ndefgh\nij\n")
+	v.EndEdit(e)
+
+	tests := []struct {
+		line, col int
+		exp       int
+	}{
+		{2, 0, 4},
+		{2, 3, 6},
+		{2, -1, 9},
+		{2, 100, 9},
+		{1, -2, 2},
+		{-1, 0, 13},
+		{-2, 2, 11},
+		{100, 1, 13},
+		{0, 1, 0},
+	}
+	for i, test := range tests {
+		v.Sel().Clear()
+		v.Sel().AddAll([]text.Region{{1, 1}, {5, 7}})
+		ed.CommandHandler().RunTextCommand(v, "goto_line", backend.Args{"line": test.line, "column": test.col})
+		if sr := v.Sel().Regions(); len(sr) != 1 || sr[0] != (text.Region{test.exp, test.exp}) {
+			t.Errorf("Test %d: Expected the cursor at %d, but got %v", i, test.exp, sr)
+		}
+	}
+
+	fe.vr = text.Region{0, 5}
+	ed.CommandHandler().RunTextCommand(v, "goto_line", backend.Args{"line": 3})
+	if exp := (text.Region{10, 13}); fe.vr != exp {
+		t.Errorf("Expected the visible region %v, but got %v", exp, fe.vr)
+	}
+}
+
+func TestGotoLinePrompt(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc\ndefgh\nij\n")
+	v.EndEdit(e)
+
+	tests := []struct {
+		text string
+		exp  int
+	}{
+		{":2:3", 6},
+		{":3", 10},
+		{"2", 4},
+		{":-1", 13},
+		{":x", 13},
+		{":1:2:3", 13},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunTextCommand(v, "goto_line_prompt", backend.Args{"text": test.text})
+		if sr := v.Sel().Regions(); len(sr) != 1 || sr[0] != (text.Region{test.exp, test.exp}) {
+			t.Errorf("Test %d: Expected the cursor at %d, but got %v", i, test.exp, sr)
+		}
+	}
+
+	for _, s := range []string{":x", ":1:2:3", ":1:y"} {
+		if _, _, err := parseGotoLine(s); err == nil {
+			t.Errorf("Expected an error parsing %q", s)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/render"
+	"github.com/limetext/text"
+)
+
+const (
+	limeCmdIncrementalFind = "lime.cmd.incremental_find"
+	// The key of the regions marking the other visible matches.
+	incrementalFindKey = "incremental_find"
+)
+
+type (
+	// IncrementalFind Command is run by the frontend every time the
+	// search text of an incremental search changes. It selects the
+	// first match at or after the cursor the search started at,
+	// wrapping around to the beginning of the buffer, and marks the
+	// other matches in the visible region. The search goes on until
+	// either CommitIncrementalFind or CancelIncrementalFind is run.
+	IncrementalFind struct {
+		backend.BypassUndoCommand
+		SearchText []rune
+		// Whether SearchText is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+		// Whether only whole words are matched, defaults
+		// to the "find_whole_word" setting.
+		WholeWord findFlag
+	}
+
+	// CommitIncrementalFind Command ends the incremental search,
+	// keeping the selected match and making the search text the
+	// window's search term.
+	CommitIncrementalFind struct {
+		backend.BypassUndoCommand
+	}
+
+	// CancelIncrementalFind Command ends the incremental search,
+	// restoring the selection it started with.
+	CancelIncrementalFind struct {
+		backend.BypassUndoCommand
+	}
+
+	// incrementalFind is the state of an incremental search.
+	incrementalFind struct {
+		// The selection when the search started.
+		sel    []text.Region
+		search string
+	}
+)
+
+// Run executes the IncrementalFind command.
+func (c *IncrementalFind) Run(v *backend.View, e *backend.Edit) error {
+	s, ok := v.Settings().Get(limeCmdIncrementalFind).(*incrementalFind)
+	if !ok {
+		s = &incrementalFind{sel: v.Sel().Regions()}
+		v.Settings().Set(limeCmdIncrementalFind, s)
+	}
+	s.search = string(c.SearchText)
+	v.EraseRegions(incrementalFindKey)
+	restoreSelection(v, s.sel)
+	if s.search == "" {
+		return nil
+	}
+	f, err := newFinder(s.search, resolveFindOptions(v, c.Regex, c.CaseSensitive, c.WholeWord))
+	if err != nil {
+		return err
+	}
+
+	pos := 0
+	if len(s.sel) != 0 {
+		pos = s.sel[0].Begin()
+	}
+	m := f.find(v, pos)
+	if m == nil {
+		m = f.find(v, 0)
+	}
+	if m == nil {
+		return nil
+	}
+	v.Sel().Clear()
+	v.Sel().Add(m.Region)
+
+	vr := backend.GetEditor().Frontend().VisibleRegion(v)
+	var rs []text.Region
+	f.each(v, vr.Begin(), func(m2 *findMatch) bool {
+		if m2.Begin() >= vr.End() {
+			return false
+		}
+		if m2.Region != m.Region {
+			rs = append(rs, m2.Region)
+		}
+		return true
+	})
+	v.AddRegions(incrementalFindKey, rs, "comment", "", render.DRAW_NO_FILL)
+	return nil
+}
+
+// Run executes the CommitIncrementalFind command.
+func (c *CommitIncrementalFind) Run(v *backend.View, e *backend.Edit) error {
+	s, ok := v.Settings().Get(limeCmdIncrementalFind).(*incrementalFind)
+	if !ok {
+		return nil
+	}
+	endIncrementalFind(v)
+	if s.search != "" {
+		setSearch(v, s.search)
+	}
+	return nil
+}
+
+// Run executes the CancelIncrementalFind command.
+func (c *CancelIncrementalFind) Run(v *backend.View, e *backend.Edit) error {
+	s, ok := v.Settings().Get(limeCmdIncrementalFind).(*incrementalFind)
+	if !ok {
+		return nil
+	}
+	endIncrementalFind(v)
+	restoreSelection(v, s.sel)
+	return nil
+}
+
+func endIncrementalFind(v *backend.View) {
+	v.Settings().Erase(limeCmdIncrementalFind)
+	v.EraseRegions(incrementalFindKey)
+}
+
+func restoreSelection(v *backend.View, rs []text.Region) {
+	v.Sel().Clear()
+	v.Sel().AddAll(rs)
+}
+
+func init() {
+	register([]backend.Command{
+		&IncrementalFind{},
+		&CommitIncrementalFind{},
+		&CancelIncrementalFind{},
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestIncrementalFind(t *testing.T) {
+	ed := backend.GetEditor()
+	var fe front
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc abd abc abd abc")
+	v.EndEdit(e)
+	v.Settings().Set("find_case_sensitive", false)
+	v.Settings().Set("find_whole_word", false)
+	fe.vr = text.Region{0, 15}
+
+	tests := []struct {
+		search  string
+		sel     []text.Region
+		regions []text.Region
+	}{
+		{"a", []text.Region{{8, 9}}, []text.Region{{0, 1}, {4, 5}, {12, 13}}},
+		{"abd", []text.Region{{12, 15}}, []text.Region{{4, 7}}},
+		{"abc", []text.Region{{8, 11}}, []text.Region{{0, 3}}},
+		{"xyz", []text.Region{{6, 6}}, nil},
+		{"", []text.Region{{6, 6}}, nil},
+	}
+	for i, test := range tests {
+		v.Sel().Clear()
+		if i == 0 {
+			v.Sel().Add(text.Region{6, 6})
+		}
+		ed.CommandHandler().RunTextCommand(v, "incremental_find", backend.Args{"search_text": test.search})
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.sel) {
+			t.Errorf("Test %d: Expected selection %v, but got %v", i, test.sel, sr)
+		}
+		if rs := v.GetRegions(incrementalFindKey); len(rs) != len(test.regions) || len(rs) != 0 && !reflect.DeepEqual(rs, test.regions) {
+			t.Errorf("Test %d: Expected regions %v, but got %v", i, test.regions, rs)
+		}
+	}
+
+	ed.CommandHandler().RunTextCommand(v, "incremental_find", backend.Args{"search_text": "abd"})
+	ed.CommandHandler().RunTextCommand(v, "cancel_incremental_find", nil)
+	if exp, sr := []text.Region{{6, 6}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected cancelling to restore %v, but got %v", exp, sr)
+	}
+	if rs := v.GetRegions(incrementalFindKey); len(rs) != 0 {
+		t.Errorf("Expected the regions to be erased, but got %v", rs)
+	}
+
+	ed.CommandHandler().RunTextCommand(v, "incremental_find", backend.Args{"search_text": "abd"})
+	ed.CommandHandler().RunTextCommand(v, "commit_incremental_find", nil)
+	if exp, sr := []text.Region{{12, 15}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected committing to keep %v, but got %v", exp, sr)
+	}
+	if s := searchFor(v).searchText(); s != "abd" {
+		t.Errorf("Expected the search term abd, but got %q", s)
+	}
+	if v.Settings().Has(limeCmdIncrementalFind) {
+		t.Error("Expected the incremental search to be over")
+	}
+}
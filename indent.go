@@ -78,6 +78,66 @@ func (c *Unindent) Run(v *backend.View, e *backend.Edit) error {
 	return nil
 }
 
+// leadingWidth returns the width of the white space s starts
+// with, tabs counting up to the next tab stop, and its length.
+func leadingWidth(s string, tabSize int) (width, n int) {
+	for n < len(s) {
+		switch s[n] {
+		case ' ':
+			width++
+		case '\t':
+			width += tabSize - width%tabSize
+		default:
+			return
+		}
+		n++
+	}
+	return
+}
+
+// indentString returns white space of the given width, in tabs
+// unless the "translate_tabs_to_spaces" setting of v is set.
+func indentString(v *backend.View, width int) string {
+	if v.Settings().Bool("translate_tabs_to_spaces", false) {
+		return strings.Repeat(" ", width)
+	}
+	tabSize := v.Settings().Int("tab_size", 4)
+	return strings.Repeat("\t", width/tabSize) + strings.Repeat(" ", width%tabSize)
+}
+
+// reindent returns s pasted at r indented so that its least indented
+// line has the indentation of r's line. Unless s is auto-expanded
+// whole lines its first line goes where r is, so it's only stripped
+// of its indentation and the other lines are indented relative to
+// each other.
+func reindent(v *backend.View, r text.Region, s string, ex bool) string {
+	tabSize := v.Settings().Int("tab_size", 4)
+	dest, _ := leadingWidth(v.Substr(v.Line(r.Begin())), tabSize)
+	lines := strings.Split(s, "\n")
+	first := 0
+	if !ex {
+		_, n := leadingWidth(lines[0], tabSize)
+		lines[0] = lines[0][n:]
+		first = 1
+	}
+
+	min := -1
+	for _, l := range lines[first:] {
+		if w, n := leadingWidth(l, tabSize); n < len(l) && (min == -1 || w < min) {
+			min = w
+		}
+	}
+	for i := first; i < len(lines); i++ {
+		w, n := leadingWidth(lines[i], tabSize)
+		if n == len(lines[i]) {
+			lines[i] = ""
+		} else {
+			lines[i] = indentString(v, dest+w-min) + lines[i][n:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func init() {
 	register([]backend.Command{
 		&Indent{},
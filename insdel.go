@@ -34,7 +34,7 @@ type (
 	}
 
 	// DeleteWord Command deletes one word to right or left
-	// depending on forward variable.
+	// depending on forward variable, adding it to the kill ring.
 	DeleteWord struct {
 		backend.DefaultCommand
 		Forward bool
@@ -148,6 +148,7 @@ func (c *DeleteWord) Run(v *backend.View, e *backend.Edit) error {
 		}
 		rs = append(rs, r)
 	}
+	done := kills.add(v, killRegions(v, rs), !c.Forward)
 	sel.Clear()
 	sel.AddAll(rs)
 	if c.Forward {
@@ -155,6 +156,8 @@ func (c *DeleteWord) Run(v *backend.View, e *backend.Edit) error {
 	} else {
 		backend.GetEditor().CommandHandler().RunTextCommand(v, "left_delete", nil)
 	}
+	done()
+	killToClipboard(v)
 	return nil
 }
 
@@ -0,0 +1,200 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"sync"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+const limeCmdJumps = "lime.cmd.jumps"
+
+type (
+	// JumpBack command goes back to where the cursors were
+	// before the last large move, switching views if needed.
+	JumpBack struct {
+		backend.BypassUndoCommand
+	}
+
+	// JumpForward command undoes a JumpBack.
+	JumpForward struct {
+		backend.BypassUndoCommand
+	}
+
+	// jumpList holds the selections of a window from before
+	// the large moves, oldest first, and the position of the
+	// one jumped to last, which is len(entries) when no jump
+	// back was made since the last move.
+	jumpList struct {
+		lock    sync.Mutex
+		entries []jump
+		pos     int
+	}
+
+	jump struct {
+		v   *backend.View
+		sel []text.Region
+	}
+)
+
+// Guards the creation of jumpLists.
+var jumpLock sync.Mutex
+
+func windowJumps(w *backend.Window) *jumpList {
+	jumpLock.Lock()
+	defer jumpLock.Unlock()
+	if j, ok := w.Settings().Get(limeCmdJumps).(*jumpList); ok {
+		return j
+	}
+	j := &jumpList{}
+	w.Settings().Set(limeCmdJumps, j)
+	return j
+}
+
+func jumpListSize(w *backend.Window) int {
+	return w.Settings().Int("jump_list_size", 64)
+}
+
+// pushJump records the selection of v in its window's jump
+// list, it's called before moving the cursors far away.
+func pushJump(v *backend.View) {
+	if v == nil || v.Window() == nil {
+		return
+	}
+	w := v.Window()
+	windowJumps(w).push(jump{v, v.Sel().Regions()}, jumpListSize(w))
+}
+
+// push adds j after the current position, dropping the
+// entries jumped back over.
+func (l *jumpList) push(j jump, size int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = l.entries[:l.pos]
+	if n := len(l.entries); n == 0 || !l.entries[n-1].equal(j) {
+		l.entries = append(l.entries, j)
+	}
+	if len(l.entries) > size {
+		l.entries = l.entries[len(l.entries)-size:]
+	}
+	l.pos = len(l.entries)
+}
+
+// back returns the entry before the current position, first
+// recording cur so it can be jumped forward to again.
+func (l *jumpList) back(cur jump) (jump, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.pos == 0 {
+		return jump{}, false
+	}
+	if l.pos == len(l.entries) {
+		if cur.v != nil && !l.entries[l.pos-1].equal(cur) {
+			l.entries = append(l.entries, cur)
+		}
+		l.pos = len(l.entries) - 1
+		if l.pos == 0 {
+			return jump{}, false
+		}
+	}
+	l.pos--
+	return l.entries[l.pos], true
+}
+
+// forward returns the entry after the current position.
+func (l *jumpList) forward() (jump, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.pos+1 >= len(l.entries) {
+		return jump{}, false
+	}
+	l.pos++
+	return l.entries[l.pos], true
+}
+
+// drop removes the entries of v.
+func (l *jumpList) drop(v *backend.View) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var entries []jump
+	for i, j := range l.entries {
+		if j.v != v {
+			entries = append(entries, j)
+		} else if i < l.pos {
+			l.pos--
+		}
+	}
+	l.entries = entries
+	if l.pos > len(l.entries) {
+		l.pos = len(l.entries)
+	}
+}
+
+func (j jump) equal(o jump) bool {
+	if j.v != o.v || len(j.sel) != len(o.sel) {
+		return false
+	}
+	for i := range j.sel {
+		if j.sel[i] != o.sel[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// restore makes the view of j active and gives it
+// the selection of j.
+func (j jump) restore(w *backend.Window) {
+	if w.ActiveView() != j.v {
+		w.SetActiveView(j.v)
+	}
+	size := j.v.Size()
+	sel := j.v.Sel()
+	sel.Clear()
+	for _, r := range j.sel {
+		sel.Add(text.Region{text.Clamp(0, size, r.A), text.Clamp(0, size, r.B)})
+	}
+	if rs := sel.Regions(); len(rs) > 0 {
+		backend.GetEditor().Frontend().Show(j.v, rs[0])
+	}
+}
+
+// Run executes the JumpBack command.
+func (c *JumpBack) Run(w *backend.Window) error {
+	v := w.ActiveView()
+	var cur jump
+	if v != nil {
+		cur = jump{v, v.Sel().Regions()}
+	}
+	if j, ok := windowJumps(w).back(cur); ok {
+		j.restore(w)
+	}
+	return nil
+}
+
+// Run executes the JumpForward command.
+func (c *JumpForward) Run(w *backend.Window) error {
+	if j, ok := windowJumps(w).forward(); ok {
+		j.restore(w)
+	}
+	return nil
+}
+
+func init() {
+	register([]backend.Command{
+		&JumpBack{},
+		&JumpForward{},
+	})
+
+	backend.OnClose.Add(func(v *backend.View) {
+		if w := v.Window(); w != nil {
+			if l, ok := w.Settings().Get(limeCmdJumps).(*jumpList); ok {
+				l.drop(v)
+			}
+		}
+	})
+}
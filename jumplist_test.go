@@ -0,0 +1,74 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestJumpList(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	newView := func() *backend.View {
+		v := w.NewFile()
+		e := v.BeginEdit()
+		v.Insert(e, 0, "Hello World!\nTest123123\nAbrakadabra\n")
+		v.EndEdit(e)
+		v.SetScratch(true)
+		return v
+	}
+	v1 := newView()
+	v1.Sel().Clear()
+	v1.Sel().Add(text.Region{5, 5})
+	ch.RunTextCommand(v1, "move_to", backend.Args{"to": "bof"})
+	ch.RunTextCommand(v1, "move_to", backend.Args{"to": "eof"})
+
+	v2 := newView()
+	w.SetActiveView(v2)
+	ch.RunTextCommand(v2, "goto_line", backend.Args{"line": 2})
+
+	tests := []struct {
+		cmd string
+		v   *backend.View
+		exp text.Region
+	}{
+		{"jump_back", v2, text.Region{36, 36}},
+		{"jump_back", v1, text.Region{0, 0}},
+		{"jump_back", v1, text.Region{5, 5}},
+		{"jump_back", v1, text.Region{5, 5}},
+		{"jump_forward", v1, text.Region{0, 0}},
+		{"jump_forward", v2, text.Region{36, 36}},
+		{"jump_forward", v2, text.Region{13, 13}},
+		{"jump_forward", v2, text.Region{13, 13}},
+	}
+	for i, test := range tests {
+		ch.RunWindowCommand(w, test.cmd, nil)
+		if av := w.ActiveView(); av != test.v {
+			t.Errorf("Test %d: Expected view %d to be active, but got %d", i, test.v.Id(), av.Id())
+		}
+		if sr := test.v.Sel().Regions(); len(sr) != 1 || sr[0] != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, sr)
+		}
+	}
+
+	v2.Close()
+	if l := windowJumps(w); len(l.entries) != 2 || l.pos != 2 {
+		t.Errorf("Expected the entries of the closed view to be dropped, but got %d entries at %d", len(l.entries), l.pos)
+	}
+	for _, e := range windowJumps(w).entries {
+		if e.v == v2 {
+			t.Errorf("Expected no entries of the closed view, but got %v", e)
+		}
+	}
+	v1.Close()
+}
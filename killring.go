@@ -0,0 +1,260 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/render"
+	"github.com/limetext/text"
+)
+
+// The key of the regions holding the text inserted by the
+// last yank, so yank_pop can replace it.
+const yankKey = "lime.cmd.yank"
+
+type (
+	// KillLine command deletes from each cursor to the end of its
+	// line, or the new line if the cursor is at the end already,
+	// adding the text to the kill ring. Non-empty selections are
+	// deleted as they are.
+	KillLine struct {
+		backend.DefaultCommand
+	}
+
+	// Yank command inserts the last entry of the kill ring at the
+	// cursors, replacing the selections.
+	Yank struct {
+		backend.DefaultCommand
+	}
+
+	// YankPop command replaces the text inserted by the previous
+	// Yank or YankPop with the kill ring entry before it.
+	YankPop struct {
+		backend.DefaultCommand
+	}
+
+	// killRing holds the text deleted by the kill commands, most
+	// recent first. An entry has the text of each cursor's kill.
+	killRing struct {
+		lock    sync.Mutex
+		entries [][]string
+		// The entry the last yank inserted.
+		pos int
+		// The state of the view after the last kill or yank,
+		// to tell whether the next command follows it.
+		kill, yank viewState
+	}
+
+	viewState struct {
+		v   *backend.View
+		cc  int
+		sel []text.Region
+	}
+)
+
+// The kill ring of the editor.
+var kills killRing
+
+func stateOf(v *backend.View) viewState {
+	return viewState{v, v.ChangeCount(), v.Sel().Regions()}
+}
+
+func (s viewState) equal(o viewState) bool {
+	if s.v != o.v || s.cc != o.cc || len(s.sel) != len(o.sel) {
+		return false
+	}
+	for i := range s.sel {
+		if s.sel[i] != o.sel[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func killRingSize(v *backend.View) int {
+	return v.Settings().Int("kill_ring_size", 32)
+}
+
+// killRegions returns the text of the non-empty regions of rs.
+func killRegions(v *backend.View, rs []text.Region) []string {
+	var ss []string
+	for _, r := range rs {
+		if !r.Empty() {
+			ss = append(ss, v.Substr(r))
+		}
+	}
+	return ss
+}
+
+// add adds the killed text ss to the ring. When v didn't change
+// since the last kill, ss is joined to the last entry instead,
+// before it if prepend is set. done must be called once the text
+// is deleted.
+func (k *killRing) add(v *backend.View, ss []string, prepend bool) (done func()) {
+	done = func() {
+		k.lock.Lock()
+		defer k.lock.Unlock()
+		k.kill = stateOf(v)
+	}
+	if len(ss) == 0 {
+		return done
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if len(k.entries) > 0 && len(k.entries[0]) == len(ss) && k.kill.equal(stateOf(v)) {
+		for i, s := range ss {
+			if prepend {
+				k.entries[0][i] = s + k.entries[0][i]
+			} else {
+				k.entries[0][i] += s
+			}
+		}
+		return done
+	}
+	k.entries = append([][]string{ss}, k.entries...)
+	if size := killRingSize(v); len(k.entries) > size {
+		k.entries = k.entries[:size]
+	}
+	return done
+}
+
+// top returns the most recent entry, joined by new lines,
+// or "" if the ring is empty.
+func (k *killRing) top() string {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if len(k.entries) == 0 {
+		return ""
+	}
+	return strings.Join(k.entries[0], "\n")
+}
+
+// killToClipboard puts the most recent kill on the clipboard when
+// the "kill_to_clipboard" setting is set.
+func killToClipboard(v *backend.View) {
+	if v.Settings().Bool("kill_to_clipboard", false) {
+		backend.GetEditor().Clipboard().Set(kills.top(), false)
+	}
+}
+
+// Run executes the KillLine command.
+func (c *KillLine) Run(v *backend.View, e *backend.Edit) error {
+	sel := v.Sel()
+	rs := sel.Regions()
+	sort.Sort(regionSorter(rs))
+	for i, r := range rs {
+		if !r.Empty() {
+			continue
+		}
+		if end := v.Line(r.B).End(); end > r.B {
+			rs[i] = text.Region{r.B, end}
+		} else if end < v.Size() {
+			rs[i] = text.Region{r.B, end + 1}
+		}
+	}
+	var set text.RegionSet
+	set.AddAll(rs)
+	rs = set.Regions()
+	sort.Sort(regionSorter(rs))
+
+	done := kills.add(v, killRegions(v, rs), false)
+	shift := 0
+	ps := make([]int, len(rs))
+	for i, r := range rs {
+		ps[i] = r.Begin() - shift
+		shift += r.Size()
+	}
+	for i := len(rs) - 1; i >= 0; i-- {
+		v.Erase(e, rs[i])
+	}
+	sel.Clear()
+	for _, p := range ps {
+		sel.Add(text.Region{p, p})
+	}
+	done()
+	killToClipboard(v)
+	return nil
+}
+
+// Run executes the Yank command.
+func (c *Yank) Run(v *backend.View, e *backend.Edit) error {
+	kills.lock.Lock()
+	if len(kills.entries) == 0 {
+		kills.lock.Unlock()
+		return nil
+	}
+	kills.pos = 0
+	ss := kills.entries[0]
+	kills.lock.Unlock()
+
+	rs := v.Sel().Regions()
+	yank(v, e, rs, ss)
+	return nil
+}
+
+// Run executes the YankPop command.
+func (c *YankPop) Run(v *backend.View, e *backend.Edit) error {
+	kills.lock.Lock()
+	if len(kills.entries) == 0 || !kills.yank.equal(stateOf(v)) {
+		kills.lock.Unlock()
+		return nil
+	}
+	kills.pos = (kills.pos + 1) % len(kills.entries)
+	ss := kills.entries[kills.pos]
+	kills.lock.Unlock()
+
+	yank(v, e, v.GetRegions(yankKey), ss)
+	return nil
+}
+
+// yank replaces the regions rs with the kill ring entry ss, a piece
+// each if there are as many pieces as regions or all of them joined
+// by new lines otherwise. It leaves a cursor after each insertion.
+func yank(v *backend.View, e *backend.Edit, rs []text.Region, ss []string) {
+	sort.Sort(regionSorter(rs))
+	all := strings.Join(ss, "\n")
+	texts := make([]string, len(rs))
+	for i := range rs {
+		if len(ss) == len(rs) {
+			texts[i] = ss[i]
+		} else {
+			texts[i] = all
+		}
+	}
+
+	yanked := make([]text.Region, len(rs))
+	shift := 0
+	for i, r := range rs {
+		n := len([]rune(texts[i]))
+		yanked[i] = text.Region{r.Begin() + shift, r.Begin() + shift + n}
+		shift += n - r.Size()
+	}
+	for i := len(rs) - 1; i >= 0; i-- {
+		v.Replace(e, rs[i], texts[i])
+	}
+
+	sel := v.Sel()
+	sel.Clear()
+	for _, r := range yanked {
+		sel.Add(text.Region{r.B, r.B})
+	}
+	v.AddRegions(yankKey, yanked, "", "", render.HIDDEN)
+
+	kills.lock.Lock()
+	kills.yank = stateOf(v)
+	kills.lock.Unlock()
+}
+
+func init() {
+	register([]backend.Command{
+		&KillLine{},
+		&Yank{},
+		&YankPop{},
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func resetKills() {
+	kills.lock.Lock()
+	defer kills.lock.Unlock()
+	kills.entries = nil
+	kills.pos = 0
+	kills.kill = viewState{}
+	kills.yank = viewState{}
+}
+
+func TestKillRing(t *testing.T) {
+	ed := backend.GetEditor()
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	type step struct {
+		cmd  string
+		args backend.Args
+		// The selection to set before, if any.
+		sel     []text.Region
+		expText string
+		expSel  []text.Region
+	}
+	tests := []struct {
+		text  string
+		steps []step
+		exp   [][]string
+	}{
+		{
+			"Hello World!\nTest123123\n",
+			[]step{
+				{"kill_line", nil, []text.Region{{6, 6}}, "Hello \nTest123123\n", []text.Region{{6, 6}}},
+				{"kill_line", nil, nil, "Hello Test123123\n", []text.Region{{6, 6}}},
+				{"yank", nil, []text.Region{{0, 0}}, "World!\nHello Test123123\n", []text.Region{{7, 7}}},
+			},
+			[][]string{{"World!\n"}},
+		},
+		{
+			"abc def\nghi",
+			[]step{
+				{"delete_word", backend.Args{"forward": false}, []text.Region{{7, 7}}, "abc \nghi", []text.Region{{4, 4}}},
+				{"delete_word", backend.Args{"forward": false}, nil, "\nghi", []text.Region{{0, 0}}},
+				{"cut", nil, []text.Region{{1, 2}}, "\nhi", []text.Region{{1, 1}}},
+			},
+			[][]string{{"g"}, {"abc def"}},
+		},
+		{
+			"one two three\n",
+			[]step{
+				{"cut", nil, []text.Region{{0, 4}}, "two three\n", []text.Region{{0, 0}}},
+				{"cut", nil, []text.Region{{4, 9}}, "two \n", []text.Region{{4, 4}}},
+				{"yank", nil, nil, "two three\n", []text.Region{{9, 9}}},
+				{"yank_pop", nil, nil, "two one \n", []text.Region{{8, 8}}},
+				{"yank_pop", nil, nil, "two three\n", []text.Region{{9, 9}}},
+				{"yank_pop", nil, []text.Region{{0, 0}}, "two three\n", []text.Region{{0, 0}}},
+			},
+			[][]string{{"three"}, {"one "}},
+		},
+		{
+			"a1\nb2\n",
+			[]step{
+				{"kill_line", nil, []text.Region{{0, 0}, {3, 3}}, "\n\n", []text.Region{{0, 0}, {1, 1}}},
+				{"yank", nil, []text.Region{{1, 1}, {2, 2}}, "\na1\nb2", []text.Region{{3, 3}, {6, 6}}},
+				{"yank", nil, []text.Region{{0, 0}}, "a1\nb2\na1\nb2", []text.Region{{5, 5}}},
+			},
+			[][]string{{"a1", "b2"}},
+		},
+	}
+	for i, test := range tests {
+		resetKills()
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, test.text)
+		v.EndEdit(e)
+		for j, s := range test.steps {
+			if s.sel != nil {
+				v.Sel().Clear()
+				v.Sel().AddAll(s.sel)
+			}
+			ch.RunTextCommand(v, s.cmd, s.args)
+			if out := v.Substr(text.Region{0, v.Size()}); out != s.expText {
+				t.Errorf("Test %d, step %d: Expected %q, but got %q", i, j, s.expText, out)
+			}
+			if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, s.expSel) {
+				t.Errorf("Test %d, step %d: Expected %v, but got %v", i, j, s.expSel, sr)
+			}
+		}
+		if !reflect.DeepEqual(kills.entries, test.exp) {
+			t.Errorf("Test %d: Expected the kill ring %q, but got %q", i, test.exp, kills.entries)
+		}
+	}
+}
+
+func TestKillRingSettings(t *testing.T) {
+	ed := backend.GetEditor()
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	resetKills()
+	v.Settings().Set("kill_ring_size", 2)
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "a\nb\nc\n")
+	v.EndEdit(e)
+
+	ed.Clipboard().Set("clip", false)
+	for _, p := range []int{4, 2, 0} {
+		v.Sel().Clear()
+		v.Sel().Add(text.Region{p, p})
+		ch.RunTextCommand(v, "kill_line", nil)
+	}
+	if exp := [][]string{{"a"}, {"b"}}; !reflect.DeepEqual(kills.entries, exp) {
+		t.Errorf("Expected the kill ring %q, but got %q", exp, kills.entries)
+	}
+	if s, _ := ed.Clipboard().Get(); s != "clip" {
+		t.Errorf("Expected kill_line to leave the clipboard alone, but got %q", s)
+	}
+
+	v.Settings().Set("kill_to_clipboard", true)
+	ch.RunTextCommand(v, "kill_line", nil)
+	if s, _ := ed.Clipboard().Get(); s != "a\n" {
+		t.Errorf("Expected kill_line to set the clipboard to %q, but got %q", "a\n", s)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"sort"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/render"
+	"github.com/limetext/text"
+)
+
+// The key of the regions holding the marks, one per cursor.
+const markKey = "mark"
+
+type (
+	// SetMark command sets a mark at each cursor, replacing
+	// the previous marks.
+	SetMark struct {
+		backend.BypassUndoCommand
+	}
+
+	// SelectToMark command selects from each mark to its cursor.
+	SelectToMark struct {
+		backend.DefaultCommand
+	}
+
+	// SwapWithMark command moves each cursor to its mark and
+	// the mark to where the cursor was, selecting in between.
+	SwapWithMark struct {
+		backend.DefaultCommand
+	}
+
+	// DeleteToMark command deletes the text between each
+	// mark and its cursor.
+	DeleteToMark struct {
+		backend.DefaultCommand
+	}
+
+	// ClearMark command removes the marks.
+	ClearMark struct {
+		backend.BypassUndoCommand
+	}
+)
+
+// setMarks makes the positions ps the marks of v.
+func setMarks(v *backend.View, ps []int) {
+	rs := make([]text.Region, len(ps))
+	for i, p := range ps {
+		rs[i] = text.Region{p, p}
+	}
+	v.AddRegions(markKey, rs, "", "", render.HIDDEN|render.PERSISTENT)
+}
+
+// toMark returns the regions from the mark of each cursor of v to
+// the cursor. The marks pair with the cursors in order, the cursors
+// after the last mark pair with it. Returns nil if there's no mark.
+func toMark(v *backend.View) []text.Region {
+	ms := v.GetRegions(markKey)
+	if len(ms) == 0 {
+		return nil
+	}
+	rs := v.Sel().Regions()
+	for i, r := range rs {
+		m := ms[len(ms)-1]
+		if i < len(ms) {
+			m = ms[i]
+		}
+		rs[i] = text.Region{m.B, r.B}
+	}
+	return rs
+}
+
+// Run executes the SetMark command.
+func (c *SetMark) Run(v *backend.View, e *backend.Edit) error {
+	rs := v.Sel().Regions()
+	ps := make([]int, len(rs))
+	for i, r := range rs {
+		ps[i] = r.B
+	}
+	setMarks(v, ps)
+	return nil
+}
+
+// Run executes the SelectToMark command.
+func (c *SelectToMark) Run(v *backend.View, e *backend.Edit) error {
+	if rs := toMark(v); rs != nil {
+		v.Sel().Clear()
+		v.Sel().AddAll(rs)
+	}
+	return nil
+}
+
+// Run executes the SwapWithMark command.
+func (c *SwapWithMark) Run(v *backend.View, e *backend.Edit) error {
+	rs := toMark(v)
+	if rs == nil {
+		return nil
+	}
+	ps := make([]int, len(rs))
+	for i, r := range rs {
+		ps[i] = r.B
+		rs[i] = text.Region{r.B, r.A}
+	}
+	setMarks(v, ps)
+	v.Sel().Clear()
+	v.Sel().AddAll(rs)
+	return nil
+}
+
+// Run executes the DeleteToMark command.
+func (c *DeleteToMark) Run(v *backend.View, e *backend.Edit) error {
+	rs := toMark(v)
+	if rs == nil {
+		return nil
+	}
+	var set text.RegionSet
+	set.AddAll(rs)
+	regions := set.Regions()
+	sort.Sort(regionSorter(regions))
+
+	// Where each deleted region ends up, after the
+	// ones before it are deleted too.
+	ps := make([]int, len(regions))
+	shift := 0
+	for i, r := range regions {
+		ps[i] = r.Begin() - shift
+		shift += r.Size()
+	}
+	for i := len(regions) - 1; i >= 0; i-- {
+		v.Erase(e, regions[i])
+	}
+	v.Sel().Clear()
+	for _, p := range ps {
+		v.Sel().Add(text.Region{p, p})
+	}
+	setMarks(v, ps)
+	return nil
+}
+
+// Run executes the ClearMark command.
+func (c *ClearMark) Run(v *backend.View, e *backend.Edit) error {
+	v.EraseRegions(markKey)
+	return nil
+}
+
+func init() {
+	register([]backend.Command{
+		&SetMark{},
+		&SelectToMark{},
+		&SwapWithMark{},
+		&DeleteToMark{},
+		&ClearMark{},
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestMark(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	const input = "Hello World!\nTest123123\nAbrakadabra\n"
+	tests := []struct {
+		cmd     string
+		marks   []text.Region
+		sel     []text.Region
+		exp     []text.Region
+		expText string
+	}{
+		{
+			"select_to_mark",
+			[]text.Region{{1, 1}, {14, 14}},
+			[]text.Region{{5, 5}, {20, 20}},
+			[]text.Region{{1, 5}, {14, 20}},
+			input,
+		},
+		{
+			"select_to_mark",
+			[]text.Region{{30, 30}},
+			[]text.Region{{5, 5}, {20, 20}},
+			[]text.Region{{30, 5}},
+			input,
+		},
+		{
+			"swap_with_mark",
+			[]text.Region{{1, 1}, {14, 14}},
+			[]text.Region{{5, 5}, {20, 20}},
+			[]text.Region{{5, 1}, {20, 14}},
+			input,
+		},
+		{
+			"delete_to_mark",
+			[]text.Region{{1, 1}, {14, 14}},
+			[]text.Region{{5, 5}, {20, 20}},
+			[]text.Region{{1, 1}, {10, 10}},
+			"H World!\nT123\nAbrakadabra\n",
+		},
+		{
+			"delete_to_mark",
+			[]text.Region{{20, 20}},
+			[]text.Region{{5, 5}},
+			[]text.Region{{5, 5}},
+			"Hello123\nAbrakadabra\n",
+		},
+	}
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, input)
+		v.EndEdit(e)
+
+		v.Sel().Clear()
+		v.Sel().AddAll(test.marks)
+		ed.CommandHandler().RunTextCommand(v, "set_mark", nil)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		ed.CommandHandler().RunTextCommand(v, test.cmd, nil)
+
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, sr)
+		}
+		if out := v.Substr(text.Region{0, v.Size()}); out != test.expText {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.expText, out)
+		}
+	}
+}
+
+func TestMarkMoves(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "Hello World!\nTest123123\n")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{13, 13})
+	ed.CommandHandler().RunTextCommand(v, "set_mark", nil)
+
+	e = v.BeginEdit()
+	v.Insert(e, 0, "abc")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{5, 5})
+	ed.CommandHandler().RunTextCommand(v, "select_to_mark", nil)
+	if exp, sr := []text.Region{{16, 5}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected the mark to move with the edit, %v, but got %v", exp, sr)
+	}
+
+	ed.CommandHandler().RunTextCommand(v, "clear_mark", nil)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{5, 5})
+	ed.CommandHandler().RunTextCommand(v, "select_to_mark", nil)
+	if exp, sr := []text.Region{{5, 5}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected no mark to select to after clear_mark, but got %v", sr)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/render"
+	"github.com/limetext/text"
+)
+
+const (
+	// The key of the regions marking the matched brackets.
+	bracketMatchKey = "bracket_match"
+	// How far the brackets around a cursor are looked for,
+	// so moving the cursor stays cheap in large files.
+	bracketMatchLimit = 10000
+)
+
+// matchBrackets marks the bracket pair around each cursor of v.
+// Next to a bracket that's the bracket and its match, elsewhere
+// the enclosing pair if the "match_brackets_content" setting is
+// set. Angle brackets are matched when "match_brackets_angle" is
+// set, and nothing at all unless "match_brackets" is.
+func matchBrackets(v *backend.View) {
+	s := v.Settings()
+	if !s.Bool("match_brackets", true) {
+		v.EraseRegions(bracketMatchKey)
+		return
+	}
+	bs := newBracketScanner(v)
+	bs.limit = bracketMatchLimit
+	if s.Bool("match_brackets_angle", false) {
+		bs.opening['<'] = '>'
+		bs.closing['>'] = '<'
+	}
+	content := s.Bool("match_brackets_content", true)
+
+	var rs []text.Region
+	for _, r := range v.Sel().Regions() {
+		if !r.Empty() {
+			continue
+		}
+		if o, c := bracketPair(bs, r.B, content); o != -1 && c != -1 {
+			rs = append(rs, text.Region{o, o + 1}, text.Region{c, c + 1})
+		}
+	}
+	if len(rs) == 0 {
+		v.EraseRegions(bracketMatchKey)
+		return
+	}
+	v.AddRegions(bracketMatchKey, rs, "brackets", "", render.DRAW_NO_FILL)
+}
+
+// bracketPair returns the positions of the opening and closing
+// bracket of the pair next to or, if content is set, around pos.
+func bracketPair(bs *bracketScanner, pos int, content bool) (int, int) {
+	next, prev := bs.at(pos), bs.at(pos-1)
+	switch {
+	case bs.isOpening(next):
+		return pos, bs.findClose(pos)
+	case bs.isClosing(prev):
+		return bs.findOpen(pos - 1), pos - 1
+	case bs.isClosing(next):
+		return bs.findOpen(pos), pos
+	case bs.isOpening(prev):
+		return pos - 1, bs.findClose(pos - 1)
+	case content:
+		o := bs.enclosing(pos)
+		return o, bs.findClose(o)
+	}
+	return -1, -1
+}
+
+func init() {
+	backend.OnSelectionModified.Add(matchBrackets)
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestMatchBrackets(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "f(a[1], <b>) x")
+	v.EndEdit(e)
+
+	tests := []struct {
+		settings map[string]bool
+		sel      []text.Region
+		exp      []text.Region
+	}{
+		{nil, []text.Region{{1, 1}}, []text.Region{{1, 2}, {11, 12}}},
+		{nil, []text.Region{{12, 12}}, []text.Region{{1, 2}, {11, 12}}},
+		{nil, []text.Region{{5, 5}}, []text.Region{{3, 4}, {5, 6}}},
+		{nil, []text.Region{{4, 4}, {13, 13}}, []text.Region{{3, 4}, {5, 6}}},
+		{nil, []text.Region{{8, 8}}, []text.Region{{1, 2}, {11, 12}}},
+		{map[string]bool{"match_brackets_angle": true}, []text.Region{{9, 9}}, []text.Region{{8, 9}, {10, 11}}},
+		{map[string]bool{"match_brackets_content": false}, []text.Region{{8, 8}}, nil},
+		{map[string]bool{"match_brackets": false}, []text.Region{{1, 1}}, nil},
+		{nil, []text.Region{{1, 3}}, nil},
+	}
+	for i, test := range tests {
+		for _, k := range []string{"match_brackets", "match_brackets_angle", "match_brackets_content"} {
+			v.Settings().Erase(k)
+		}
+		for k, b := range test.settings {
+			v.Settings().Set(k, b)
+		}
+		// Ending an edit which changed the selection
+		// fires OnSelectionModified.
+		v.Sel().Clear()
+		e := v.BeginEdit()
+		v.Sel().AddAll(test.sel)
+		v.EndEdit(e)
+
+		if rs := v.GetRegions(bracketMatchKey); len(rs) != len(test.exp) || len(rs) != 0 && !reflect.DeepEqual(rs, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, rs)
+		}
+	}
+}
+
+func TestMatchBracketsLimit(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "("+strings.Repeat("x", bracketMatchLimit)+")")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	e = v.BeginEdit()
+	v.Sel().Add(text.Region{0, 0})
+	v.EndEdit(e)
+	if rs := v.GetRegions(bracketMatchKey); len(rs) != 0 {
+		t.Errorf("Expected brackets further apart than the limit not to match, but got %v", rs)
+	}
+}
@@ -157,75 +157,19 @@ func (c *MoveTo) Run(v *backend.View, e *backend.Edit) error {
 			return line.A
 		})
 	case BOF:
+		pushJump(v)
 		moveAction(v, c.Extend, true, func(r text.Region) int {
 			return 0
 		})
 	case EOF:
+		pushJump(v)
 		moveAction(v, c.Extend, true, func(r text.Region) int {
 			return v.Size()
 		})
 	case Brackets:
-		moveAction(v, c.Extend, true, func(r text.Region) (pos int) {
-			var (
-				of          int
-				co          = 1
-				str, br, rv string
-				opening     = "([{"
-				closing     = ")]}"
-			)
-			pos = r.B
-
-			// next and before character
-			n := v.Substr(text.Region{r.B, r.B + 1})
-			b := v.Substr(text.Region{r.B, r.B - 1})
-			if strings.ContainsAny(n, opening) {
-				// TODO: Maybe it's better to use sth like view.FindByClass or even
-				// view.FindByClass() function itself instead of getting whole text
-				// and looping through it. With using view.FindByClass() function
-				// backward we won't need to reverse the text anymore
-				str = v.Substr(text.Region{r.B + 1, v.Size()})
-				br = n
-				rv = revert(n)
-				of = 2
-			} else if strings.ContainsAny(b, closing) {
-				// TODO: same as above
-				str = v.Substr(text.Region{0, r.B - 1})
-				br = b
-				rv = revert(b)
-				str = reverse(str)
-				co = -1
-				of = -2
-			} else if strings.ContainsAny(n, closing) {
-				// TODO: same as above
-				str = v.Substr(text.Region{0, r.B - 1})
-				br = n
-				rv = revert(n)
-				str = reverse(str)
-				co = -1
-				of = -1
-			} else {
-				// TODO: same as above
-				str = v.Substr(text.Region{r.B, v.Size()})
-				bef := v.Substr(text.Region{0, r.B})
-				if p := strings.LastIndexAny(bef, opening); p == -1 {
-					return
-				} else {
-					br = string(bef[p])
-					rv = revert(br)
-				}
-			}
-			count := 1
-			for i, c := range str {
-				if ch := string(c); ch == br {
-					count++
-				} else if ch == rv {
-					count--
-				}
-				if count == 0 {
-					return i*co + r.B + of
-				}
-			}
-			return
+		bs := newBracketScanner(v)
+		moveAction(v, c.Extend, true, func(r text.Region) int {
+			return moveToBracket(bs, r.B)
 		})
 	default:
 		return fmt.Errorf("move_to: Unimplemented 'to' action: %d", c.To)
@@ -334,6 +278,7 @@ func (c *Move) Run(v *backend.View, e *backend.Edit) error {
 				backend.CLASS_LINE_END|backend.CLASS_LINE_START)
 		})
 	case Pages:
+		pushJump(v)
 		fe := backend.GetEditor().Frontend()
 		vr := fe.VisibleRegion(v)
 		ls := v.Lines(vr)
@@ -416,30 +361,30 @@ func (c *Move) Default(key string) interface{} {
 	return nil
 }
 
-func revert(c string) string {
-	switch c {
-	case "(":
-		return ")"
-	case ")":
-		return "("
-	case "[":
-		return "]"
-	case "]":
-		return "["
-	case "{":
-		return "}"
-	case "}":
-		return "{"
-	}
-	return ""
-}
-
-func reverse(s string) string {
-	r := []rune(s)
-	for i, j := 0, len(r)-1; i < len(r)/2; i, j = i+1, j-1 {
-		r[i], r[j] = r[j], r[i]
+// moveToBracket returns where moving to the brackets from pos
+// leads. Next to a bracket that's the other side of its matching
+// bracket, elsewhere it's the closing bracket enclosing pos.
+func moveToBracket(bs *bracketScanner, pos int) int {
+	next, prev := bs.at(pos), bs.at(pos-1)
+	switch {
+	case bs.isOpening(next):
+		if p := bs.findClose(pos); p != -1 {
+			return p + 1
+		}
+	case bs.isClosing(prev):
+		if p := bs.findOpen(pos - 1); p != -1 {
+			return p
+		}
+	case bs.isClosing(next):
+		if p := bs.findOpen(pos); p != -1 {
+			return p + 1
+		}
+	default:
+		if p := bs.findClose(bs.enclosing(pos)); p != -1 {
+			return p
+		}
 	}
-	return string(r)
+	return pos
 }
 
 func (c *ScrollLines) Run(v *backend.View, e *backend.Edit) error {
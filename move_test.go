@@ -578,11 +578,14 @@ type front struct {
 	vr            text.Region
 	defaultAction bool
 	files         []string
+	message       string
 }
 
 func (f *front) StatusMessage(msg string) {}
 func (f *front) ErrorMessage(msg string)  {}
-func (f *front) MessageDialog(msg string) {}
+func (f *front) MessageDialog(msg string) {
+	f.message = msg
+}
 
 func (f *front) SetDefaultAction(action bool) {
 	f.defaultAction = action
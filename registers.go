@@ -0,0 +1,148 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/limetext/backend"
+)
+
+type (
+	// ShowRegisters command lists the registers Copy, Cut
+	// and Paste can use and their content.
+	ShowRegisters struct {
+		backend.BypassUndoCommand
+	}
+
+	// registers holds the named registers a to z.
+	registers struct {
+		lock    sync.Mutex
+		entries map[rune]registerEntry
+	}
+
+	// registerEntry holds the text of each selection copied
+	// into it, like the kill ring.
+	registerEntry struct {
+		pieces   []string
+		expanded bool
+	}
+)
+
+const (
+	// The read-only register of the search term.
+	searchRegister = '/'
+	// The read-only register of the file name.
+	fileNameRegister = '%'
+)
+
+// The registers of the editor.
+var regs = registers{entries: make(map[rune]registerEntry)}
+
+// checkRegister returns an error if name isn't a register,
+// or if write is set and it's read-only.
+func checkRegister(name string, write bool) error {
+	rs := []rune(name)
+	switch {
+	case name == "":
+		return nil
+	case len(rs) != 1:
+		return fmt.Errorf("Invalid register: %q", name)
+	case rs[0] >= 'a' && rs[0] <= 'z', rs[0] >= 'A' && rs[0] <= 'Z':
+		return nil
+	case rs[0] == searchRegister, rs[0] == fileNameRegister:
+		if write {
+			return fmt.Errorf("Register %q is read-only", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("Invalid register: %q", name)
+}
+
+// setRegister puts the pieces ss in the register name, appending
+// them to its content if name is uppercase.
+func setRegister(name string, ss []string, ex bool) error {
+	if err := checkRegister(name, true); err != nil {
+		return err
+	}
+	r := []rune(name)[0]
+	regs.lock.Lock()
+	defer regs.lock.Unlock()
+	if unicode.IsUpper(r) {
+		r = unicode.ToLower(r)
+		if old, ok := regs.entries[r]; ok {
+			if len(old.pieces) == len(ss) {
+				for i, s := range ss {
+					ss[i] = old.pieces[i] + s
+				}
+			} else {
+				ss = []string{strings.Join(old.pieces, "\n") + strings.Join(ss, "\n")}
+			}
+			ex = ex && old.expanded
+		}
+	}
+	regs.entries[r] = registerEntry{ss, ex}
+	return nil
+}
+
+// getRegister returns the content of the register name.
+func getRegister(v *backend.View, name string) ([]string, bool, error) {
+	if err := checkRegister(name, false); err != nil {
+		return nil, false, err
+	}
+	r := unicode.ToLower([]rune(name)[0])
+	switch r {
+	case searchRegister:
+		return []string{searchFor(v).searchText()}, false, nil
+	case fileNameRegister:
+		return []string{v.FileName()}, false, nil
+	}
+	regs.lock.Lock()
+	defer regs.lock.Unlock()
+	reg := regs.entries[r]
+	return reg.pieces, reg.expanded, nil
+}
+
+// Run executes the ShowRegisters command.
+func (c *ShowRegisters) Run(v *backend.View, e *backend.Edit) error {
+	var buf bytes.Buffer
+	show := func(r rune, ss []string) {
+		s := strings.Replace(strings.Join(ss, "\n"), "\n", `\n`, -1)
+		if rs := []rune(s); len(rs) > 60 {
+			s = string(rs[:60]) + "…"
+		}
+		fmt.Fprintf(&buf, "\"%c  %s\n", r, s)
+	}
+
+	regs.lock.Lock()
+	var names []rune
+	for r := range regs.entries {
+		names = append(names, r)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	for _, r := range names {
+		show(r, regs.entries[r].pieces)
+	}
+	regs.lock.Unlock()
+
+	for _, r := range []rune{searchRegister, fileNameRegister} {
+		if ss, _, _ := getRegister(v, string(r)); len(ss) > 0 && ss[0] != "" {
+			show(r, ss)
+		}
+	}
+	backend.GetEditor().Frontend().MessageDialog(buf.String())
+	return nil
+}
+
+func init() {
+	register([]backend.Command{
+		&ShowRegisters{},
+	})
+}
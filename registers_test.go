@@ -0,0 +1,89 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestRegisters(t *testing.T) {
+	var fe front
+	ed := backend.GetEditor()
+	ed.SetFrontend(&fe)
+	cb := &dummyClipboard{}
+	ed.UseClipboard(cb)
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	v.SetFileName("x.txt")
+	setSearch(v, "find")
+
+	regs.lock.Lock()
+	regs.entries = make(map[rune]registerEntry)
+	regs.lock.Unlock()
+
+	const input = "abc def\nghi\n"
+	tests := []struct {
+		cmd      string
+		register string
+		sel      []text.Region
+		expBuf   string
+		expErr   bool
+	}{
+		{"copy", "a", []text.Region{{0, 3}, {4, 7}}, input, false},
+		{"paste", "a", []text.Region{{8, 8}, {10, 10}}, "abc def\nabcghdefi\n", false},
+		{"paste", "a", []text.Region{{8, 8}}, "abc def\nabc\ndefghi\n", false},
+		{"copy", "A", []text.Region{{8, 11}}, input, false},
+		{"paste", "a", []text.Region{{0, 0}}, "abc\ndefghiabc def\nghi\n", false},
+		{"copy", "b", []text.Region{{0, 3}, {4, 7}}, input, false},
+		{"copy", "B", []text.Region{{1, 2}, {8, 9}}, input, false},
+		{"paste", "b", []text.Region{{11, 11}, {12, 12}}, "abc def\nghiabcb\ndefg", false},
+		{"cut", "c", []text.Region{{9, 9}}, "abc def\n", false},
+		{"paste", "c", []text.Region{{0, 0}}, "ghi\nabc def\nghi\n", false},
+		{"paste", "/", []text.Region{{0, 0}}, "find" + input, false},
+		{"paste", "%", []text.Region{{0, 0}}, "x.txt" + input, false},
+		{"paste", "z", []text.Region{{0, 3}}, input, false},
+		{"copy", "/", []text.Region{{0, 3}}, input, true},
+		{"cut", "%", []text.Region{{0, 3}}, input, true},
+		{"paste", "ab", []text.Region{{0, 0}}, input, true},
+		{"paste", "1", []text.Region{{0, 0}}, input, true},
+	}
+	for i, test := range tests {
+		cb.Set("clip", false)
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, input)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+
+		err := ch.RunTextCommand(v, test.cmd, backend.Args{"register": test.register})
+		if (err != nil) != test.expErr {
+			t.Errorf("Test %d: Expected the error to be %v, but got %v", i, test.expErr, err)
+		}
+		if b := v.Substr(text.Region{0, v.Size()}); b != test.expBuf {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.expBuf, b)
+		}
+		if s, _ := cb.Get(); s != "clip" {
+			t.Errorf("Test %d: Expected the clipboard to be left alone, but got %q", i, s)
+		}
+	}
+
+	ch.RunTextCommand(v, "show_registers", nil)
+	for _, exp := range []string{`"a  abc\ndefghi`, `"c  ghi\n`, `"/  find`, `"%  x.txt`} {
+		if !strings.Contains(fe.message, exp) {
+			t.Errorf("Expected %q in the registers shown, but got %q", exp, fe.message)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/limetext/backend"
+)
+
+// The name of the view replace in files previews are written to.
+const replaceResultsName = "Replace Results"
+
+type (
+	// ReplaceInFiles Command replaces SearchText with ReplaceText in
+	// the files of the project folders. The affected lines are listed
+	// before and after the replacement in the "Replace Results" view,
+	// and once confirmed the files open in the window are edited, as
+	// a single undo per view, while the others are written directly.
+	ReplaceInFiles struct {
+		backend.DefaultCommand
+		SearchText  []rune
+		ReplaceText []rune
+		// Comma separated glob patterns, only files matching
+		// one of them are searched when given.
+		Include string
+		// Comma separated glob patterns of files and folders
+		// which are not searched.
+		Exclude string
+		// Whether SearchText is a regular expression,
+		// defaults to the "find_regex" setting.
+		Regex findFlag
+		// Whether the search is case sensitive, defaults
+		// to the "find_case_sensitive" setting.
+		CaseSensitive findFlag
+	}
+)
+
+// Run executes the ReplaceInFiles command.
+func (c *ReplaceInFiles) Run(w *backend.Window) error {
+	if len(c.SearchText) == 0 {
+		return nil
+	}
+	search, replace := string(c.SearchText), string(c.ReplaceText)
+	f, err := newFinder(search, findOptions{
+		regex:         c.Regex.resolve(w.Settings(), "find_regex"),
+		caseSensitive: c.CaseSensitive.resolve(w.Settings(), "find_case_sensitive"),
+	})
+	if err != nil {
+		return err
+	}
+	s := windowSearch(w)
+	s.setSearch(search, searchHistorySize(w))
+	s.setReplace(replace, searchHistorySize(w))
+
+	ctx, cancel := s.startFindInFiles()
+	defer cancel()
+	_, rs := searchFiles(ctx, w, f, c.Include, c.Exclude)
+	if ctx.Err() != nil || len(rs) == 0 {
+		return nil
+	}
+
+	var (
+		buf     bytes.Buffer
+		matches int
+	)
+	fmt.Fprintf(&buf, "Replacing %q with %q\n", search, replace)
+	for _, r := range rs {
+		fmt.Fprintf(&buf, "\n%s:\n", r.path)
+		for _, l := range r.lines {
+			fmt.Fprintf(&buf, "%5d- %s\n", l.row, l.text)
+			fmt.Fprintf(&buf, "%5d+ %s\n", l.row, f.replaceString(l.text, replace))
+		}
+		matches += r.matches
+	}
+	fmt.Fprintf(&buf, "\n%d matches across %d files\n", matches, len(rs))
+	showResults(w, replaceResultsName, `^ +([0-9]+)-`, buf.String())
+
+	fe := backend.GetEditor().Frontend()
+	msg := fmt.Sprintf("Replace %d matches across %d files?", matches, len(rs))
+	if !fe.OkCancelDialog(msg, "Replace") {
+		return nil
+	}
+
+	var failed []string
+	for _, r := range rs {
+		if v := fileView(w, r.path); v != nil {
+			replaceInView(v, f, r, replace)
+		} else if err := replaceInFile(f, r, replace); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", r.path, err))
+		}
+	}
+	if len(failed) != 0 {
+		err := fmt.Errorf("Failed to replace in:\n%s", strings.Join(failed, "\n"))
+		fe.ErrorMessage(err.Error())
+		return err
+	}
+	return nil
+}
+
+// replaceInView replaces the matches on the lines of r
+// in v as a single edit.
+func replaceInView(v *backend.View, f *finder, r fileResult, replace string) {
+	e := v.BeginEdit()
+	defer v.EndEdit(e)
+	// Backwards so the lines before aren't moved.
+	for i := len(r.lines) - 1; i >= 0; i-- {
+		l := v.Line(v.TextPoint(r.lines[i].row-1, 0))
+		v.Replace(e, l, f.replaceString(v.Substr(l), replace))
+	}
+}
+
+// replaceInFile replaces the matches on the lines of r in
+// the file on disk.
+func replaceInFile(f *finder, r fileResult, replace string) error {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+	for _, l := range r.lines {
+		if l.row <= len(lines) {
+			lines[l.row-1] = f.replaceString(lines[l.row-1], replace)
+		}
+	}
+	return ioutil.WriteFile(r.path, []byte(strings.Join(lines, "\n")), fi.Mode())
+}
+
+func init() {
+	register([]backend.Command{
+		&ReplaceInFiles{},
+	})
+}
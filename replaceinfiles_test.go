@@ -0,0 +1,114 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestReplaceInFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replaceinfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	write := func() {
+		for name, data := range map[string]string{
+			"a.go": "func foo() {}\n\nfoo()\n",
+			"b.go": "x := foo_1\n",
+		} {
+			if err := ioutil.WriteFile(p(name), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(p(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	ed := backend.GetEditor()
+	var fe front
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+	args := backend.Args{"search_text": `foo(_\d)?`, "replace_text": `bar\1`, "regex": true}
+
+	write()
+	fe.defaultAction = false
+	ed.CommandHandler().RunWindowCommand(w, "replace_in_files", args)
+	exp := fmt.Sprintf("Replacing %q with %q\n\n%s:\n    1- func foo() {}\n    1+ func bar() {}\n    3- foo()\n    3+ bar()\n\n%s:\n    1- x := foo_1\n    1+ x := bar_1\n\n3 matches across 2 files\n",
+		`foo(_\d)?`, `bar\1`, p("a.go"), p("b.go"))
+	if v := w.ActiveView(); v.Name() != replaceResultsName {
+		t.Errorf("Expected the replace results view to be active, but got %q", v.Name())
+	} else if out := v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected preview\n%s\nbut got\n%s", exp, out)
+	}
+	if out := read("a.go"); out != "func foo() {}\n\nfoo()\n" {
+		t.Errorf("Expected the files to be unchanged when cancelled, but got %q", out)
+	}
+
+	v := w.OpenFile(p("a.go"), 0)
+	defer v.SetScratch(true)
+	fe.defaultAction = true
+	ed.CommandHandler().RunWindowCommand(w, "replace_in_files", args)
+	if exp, out := "func bar() {}\n\nbar()\n", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected the open view to be %q, but got %q", exp, out)
+	}
+	if exp, out := "func foo() {}\n\nfoo()\n", read("a.go"); out != exp {
+		t.Errorf("Expected the open file to be unsaved %q, but got %q", exp, out)
+	}
+	if exp, out := "x := bar_1\n", read("b.go"); out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+
+	ed.CommandHandler().RunTextCommand(v, "undo", nil)
+	if exp, out := "func foo() {}\n\nfoo()\n", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected a single undo to revert the view to %q, but got %q", exp, out)
+	}
+}
+
+func TestReplaceInFilesFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replaceinfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(path, []byte("foo\n"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		f.Close()
+		t.Skip("Read only files are writable, running as root?")
+	}
+
+	ed := backend.GetEditor()
+	var fe front
+	fe.defaultAction = true
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+
+	c := &ReplaceInFiles{SearchText: []rune("foo"), ReplaceText: []rune("bar")}
+	if err := c.Run(w); err == nil {
+		t.Error("Expected an error replacing in a read only file")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+const limeCmdResult = "lime.cmd.result"
+
+type (
+	// NextResult Command opens the location of the next entry
+	// listed in the window's results view and places the cursor
+	// there. A results view is any view with the "result_file_regex"
+	// setting, e.g the "Find Results" view or build output.
+	//
+	// "result_file_regex" is matched against each line of the view,
+	// its first group being the file name and the optional second and
+	// third the line and column. Lines only naming a file are the
+	// header of the following lines matching "result_line_regex",
+	// whose first group is the line and optional second the column.
+	// Relative file names are resolved against "result_base_dir".
+	NextResult struct {
+		backend.DefaultCommand
+	}
+
+	// PrevResult Command is like NextResult but goes
+	// to the previous entry of the results view.
+	PrevResult struct {
+		backend.DefaultCommand
+	}
+
+	// result is a location listed in a results view.
+	result struct {
+		file string
+		// 1-based, col is 0 when not given.
+		row, col int
+		// The entry's line in the results view.
+		line text.Region
+	}
+)
+
+// Run executes the NextResult command.
+func (c *NextResult) Run(w *backend.Window) error {
+	return stepResult(w, true)
+}
+
+// Run executes the PrevResult command.
+func (c *PrevResult) Run(w *backend.Window) error {
+	return stepResult(w, false)
+}
+
+// resultsView returns the active view if it's a results view,
+// or else the first results view of the window.
+func resultsView(w *backend.Window) *backend.View {
+	if v := w.ActiveView(); v != nil && v.Settings().Has("result_file_regex") {
+		return v
+	}
+	for _, v := range w.Views() {
+		if v.Settings().Has("result_file_regex") {
+			return v
+		}
+	}
+	return nil
+}
+
+func compileSetting(v *backend.View, setting string) (*regexp.Regexp, error) {
+	p := v.Settings().String(setting, "")
+	if p == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, &PatternError{Pattern: p, Err: err}
+	}
+	return re, nil
+}
+
+func submatchInt(m []string, i int) int {
+	if i >= len(m) {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[i])
+	return n
+}
+
+// parseResults returns the locations listed in the results view v.
+func parseResults(v *backend.View) ([]result, error) {
+	fileRe, err := compileSetting(v, "result_file_regex")
+	if err != nil || fileRe == nil {
+		return nil, err
+	}
+	lineRe, err := compileSetting(v, "result_line_regex")
+	if err != nil {
+		return nil, err
+	}
+	base := v.Settings().String("result_base_dir", "")
+
+	var (
+		rs   []result
+		file string
+		pos  int
+	)
+	for _, l := range strings.Split(v.Substr(text.Region{0, v.Size()}), "\n") {
+		line := text.Region{pos, pos + utf8.RuneCountInString(l)}
+		pos = line.B + 1
+		if m := fileRe.FindStringSubmatch(l); m != nil && len(m) > 1 {
+			file = m[1]
+			if base != "" && !filepath.IsAbs(file) {
+				file = filepath.Join(base, file)
+			}
+			if row := submatchInt(m, 2); row > 0 {
+				rs = append(rs, result{file, row, submatchInt(m, 3), line})
+			}
+		} else if lineRe != nil && file != "" {
+			if m := lineRe.FindStringSubmatch(l); m != nil {
+				if row := submatchInt(m, 1); row > 0 {
+					rs = append(rs, result{file, row, submatchInt(m, 2), line})
+				}
+			}
+		}
+	}
+	return rs, nil
+}
+
+// stepResult goes to the next or previous entry of the window's
+// results view, wrapping around at either end.
+func stepResult(w *backend.Window, forward bool) error {
+	rv := resultsView(w)
+	if rv == nil {
+		return nil
+	}
+	rs, err := parseResults(rv)
+	if err != nil || len(rs) == 0 {
+		return err
+	}
+	i := rv.Settings().Int(limeCmdResult, -1)
+	if forward {
+		i++
+	} else if i < 0 {
+		i = len(rs) - 1
+	} else {
+		i--
+	}
+	i = (i + len(rs)) % len(rs)
+	rv.Settings().Set(limeCmdResult, i)
+
+	r := rs[i]
+	rv.Sel().Clear()
+	rv.Sel().Add(text.Region{r.line.A, r.line.A})
+
+	pushJump(w.ActiveView())
+	v := fileView(w, r.file)
+	if v == nil {
+		v = w.OpenFile(r.file, 0)
+	}
+	w.SetActiveView(v)
+	p := v.TextPoint(r.row-1, 0)
+	if r.col > 1 {
+		p = text.Clamp(p, v.Line(p).End(), p+r.col-1)
+	}
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{p, p})
+	backend.GetEditor().Frontend().Show(v, text.Region{p, p})
+	return nil
+}
+
+// fileView returns the view of w which has the file
+// at path open, or nil if there is none.
+func fileView(w *backend.Window, path string) *backend.View {
+	if p, err := filepath.Abs(path); err == nil {
+		path = p
+	}
+	for _, v := range w.Views() {
+		if v.FileName() == path {
+			return v
+		}
+	}
+	return nil
+}
+
+func init() {
+	register([]backend.Command{
+		&NextResult{},
+		&PrevResult{},
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+func TestNextPrevResult(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, data := range map[string]string{
+		"a.go": "package a\n\nfunc A() {}\n",
+		"b.go": "package b\nvar b = 1\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ed := backend.GetEditor()
+	var fe front
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+
+	rv := w.NewFile()
+	rv.SetScratch(true)
+	rv.Settings().Set("result_file_regex", `^([^ :]+):(?:([0-9]+):([0-9]+):)?`)
+	rv.Settings().Set("result_line_regex", `^ +([0-9]+):`)
+	rv.Settings().Set("result_base_dir", dir)
+	e := rv.BeginEdit()
+	rv.Insert(e, 0, "a.go:3:6: undefined: A\nb.go:\n    2: var b = 1\n   10: out of range\nbuild failed\n")
+	rv.EndEdit(e)
+
+	tests := []struct {
+		cmd     string
+		file    string
+		sel     text.Region
+		resultA int
+	}{
+		{"next_result", "a.go", text.Region{16, 16}, 0},
+		{"next_result", "b.go", text.Region{10, 10}, 29},
+		{"next_result", "b.go", text.Region{20, 20}, 46},
+		{"next_result", "a.go", text.Region{16, 16}, 0},
+		{"prev_result", "b.go", text.Region{20, 20}, 46},
+		{"prev_result", "b.go", text.Region{10, 10}, 29},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunWindowCommand(w, test.cmd, nil)
+		v := w.ActiveView()
+		if exp := filepath.Join(dir, test.file); v.FileName() != exp {
+			t.Errorf("Test %d: Expected %s to be active, but got %s", i, exp, v.FileName())
+			continue
+		}
+		if exp, sr := []text.Region{test.sel}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+			t.Errorf("Test %d: Expected %s, but got %s", i, exp, sr)
+		}
+		if exp, sr := []text.Region{{test.resultA, test.resultA}}, rv.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+			t.Errorf("Test %d: Expected results view selection %s, but got %s", i, exp, sr)
+		}
+	}
+	// The results view and one view per file.
+	if n := len(w.Views()); n != 3 {
+		t.Errorf("Expected opened files to be reused, but got %d views", n)
+	}
+	for _, v := range w.Views() {
+		v.SetScratch(true)
+	}
+}
+
+func TestNextResultFindResults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("x\nfoo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ed := backend.GetEditor()
+	var fe front
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+
+	ed.CommandHandler().RunWindowCommand(w, "find_in_files", backend.Args{"search_text": "foo"})
+	ed.CommandHandler().RunWindowCommand(w, "next_result", nil)
+	v := w.ActiveView()
+	if exp := filepath.Join(dir, "a.txt"); v.FileName() != exp {
+		t.Fatalf("Expected %s to be active, but got %s", exp, v.FileName())
+	}
+	if exp, sr := []text.Region{{2, 2}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %s, but got %s", exp, sr)
+	}
+	v.SetScratch(true)
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"strings"
+)
+
+// scopeMatches reports whether scope, a scope name as returned by
+// View.ScopeName, satisfies the scope selector sel.
+//
+// The selector is a comma separated list of alternatives. Each is
+// a space separated path of scope names, which must all be found in
+// scope in that order, optionally followed by paths prefixed with
+// "-" which must not be found. A name matches the scope names it is
+// a prefix of, e.g "string" matches "string.quoted.double". Empty
+// selectors match any scope, so do ones consisting of exclusions only.
+func scopeMatches(scope, sel string) bool {
+	if strings.TrimSpace(sel) == "" {
+		return true
+	}
+	names := strings.Fields(scope)
+	for _, alt := range strings.Split(sel, ",") {
+		if altMatches(names, alt) {
+			return true
+		}
+	}
+	return false
+}
+
+func altMatches(names []string, alt string) bool {
+	var (
+		include []string
+		exclude [][]string
+	)
+	for _, f := range strings.Fields(alt) {
+		switch {
+		case strings.HasPrefix(f, "-"):
+			exclude = append(exclude, nil)
+			if f = f[1:]; f == "" {
+				continue
+			}
+			fallthrough
+		case len(exclude) != 0:
+			exclude[len(exclude)-1] = append(exclude[len(exclude)-1], f)
+		default:
+			include = append(include, f)
+		}
+	}
+	if !pathMatches(names, include) {
+		return false
+	}
+	for _, ex := range exclude {
+		if len(ex) != 0 && pathMatches(names, ex) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathMatches reports whether each of path matches
+// one of names, in order.
+func pathMatches(names, path []string) bool {
+	for _, n := range names {
+		if len(path) == 0 {
+			break
+		}
+		if n == path[0] || strings.HasPrefix(n, path[0]+".") {
+			path = path[1:]
+		}
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/backend/parser"
+	"github.com/limetext/text"
+	qp "github.com/quarnster/parser"
+)
+
+const testSyntaxFile = "testdata/Test.tmLanguage"
+
+type (
+	// testSyntax is a minimal syntax for the tests depending on scopes.
+	// Text between double quotes is scoped string.quoted.double and
+	// text from // to the end of the line comment.line, all inside
+	// source.test.
+	testSyntax struct{}
+
+	testParser struct {
+		data string
+	}
+)
+
+func (s testSyntax) Parser(data string) (parser.Parser, error) {
+	return &testParser{data}, nil
+}
+
+func (s testSyntax) Name() string {
+	return "Test"
+}
+
+func (s testSyntax) FileTypes() []string {
+	return []string{"limetest"}
+}
+
+func (p *testParser) Parse() (*qp.Node, error) {
+	rs := []rune(p.data)
+	root := &qp.Node{Name: "source.test", Range: text.Region{0, len(rs)}}
+	for i := 0; i < len(rs); i++ {
+		j := i
+		switch {
+		case rs[i] == '"':
+			for j++; j < len(rs) && rs[j] != '"' && rs[j] != '\n'; j++ {
+			}
+			if j < len(rs) && rs[j] == '"' {
+				j++
+			}
+			root.Append(&qp.Node{Name: "string.quoted.double", Range: text.Region{i, j}})
+		case rs[i] == '/' && i+1 < len(rs) && rs[i+1] == '/':
+			for ; j < len(rs) && rs[j] != '\n'; j++ {
+			}
+			root.Append(&qp.Node{Name: "comment.line", Range: text.Region{i, j}})
+		default:
+			continue
+		}
+		i = j - 1
+	}
+	return root, nil
+}
+
+// setTestSyntax sets the content of v to s, gives it the test
+// syntax and waits for it to be parsed.
+func setTestSyntax(t *testing.T, v *backend.View, s string) {
+	backend.GetEditor().AddSyntax(testSyntaxFile, testSyntax{})
+	// Setting the syntax first makes sure the parse finishing
+	// at the edit's change count used it.
+	v.SetSyntaxFile(testSyntaxFile)
+	e := v.BeginEdit()
+	v.Erase(e, text.Region{0, v.Size()})
+	v.Insert(e, 0, s)
+	v.EndEdit(e)
+	for i := 0; i < 200; i++ {
+		if n, ok := v.Settings().Get("lime.syntax.updated").(int); ok && n == v.ChangeCount() && v.ScopeName(0) != "" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the test syntax to be parsed")
+}
+
+func TestScopeMatches(t *testing.T) {
+	const scope = "source.go string.quoted.double punctuation.definition.string"
+	tests := []struct {
+		sel string
+		exp bool
+	}{
+		{"", true},
+		{"string", true},
+		{"string.quoted", true},
+		{"string.quot", false},
+		{"comment", false},
+		{"source string", true},
+		{"string source", false},
+		{"source.go punctuation", true},
+		{"-comment", true},
+		{"-string", false},
+		{"source -string", false},
+		{"source - string", false},
+		{"source -comment", true},
+		{"comment, string", true},
+		{"comment, -source", false},
+		{"source -comment.line -string.quoted", false},
+	}
+	for i, test := range tests {
+		if m := scopeMatches(scope, test.sel); m != test.exp {
+			t.Errorf("Test %d: Expected %q to be %v, but got %v", i, test.sel, test.exp, m)
+		}
+	}
+}
+
+func TestFindScope(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	v.Settings().Set("find_case_sensitive", true)
+	v.Settings().Set("find_whole_word", false)
+	v.Settings().Set("find_regex", false)
+	v.Settings().Set("find_wrap", true)
+
+	const input = "x := \"TODO\" // TODO fix\nTODO()\n"
+	tests := []struct {
+		scope string
+		exp   []text.Region
+	}{
+		{"", []text.Region{{6, 10}, {15, 19}, {24, 28}}},
+		{"string", []text.Region{{6, 10}}},
+		{"comment", []text.Region{{15, 19}}},
+		{"-comment", []text.Region{{6, 10}, {24, 28}}},
+		{"source -comment -string", []text.Region{{24, 28}}},
+	}
+	setTestSyntax(t, v, input)
+	for i, test := range tests {
+		ed.CommandHandler().RunTextCommand(v, "find_all", backend.Args{"search_text": "TODO", "scope": test.scope})
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, sr)
+		}
+	}
+
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{0, 0})
+	ed.CommandHandler().RunTextCommand(v, "find_next", backend.Args{"scope": "comment"})
+	if exp, sr := []text.Region{{15, 19}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %v, but got %v", exp, sr)
+	}
+
+	ed.CommandHandler().RunTextCommand(v, "replace_all", backend.Args{"search_text": "TODO", "replace_text": "DONE", "scope": "comment"})
+	if exp, out := "x := \"TODO\" // DONE fix\nTODO()\n", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+}
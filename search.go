@@ -0,0 +1,235 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"context"
+	"sync"
+
+	"github.com/limetext/backend"
+)
+
+const limeCmdSearch = "lime.cmd.search"
+
+type (
+	// SetSearchText Command sets the search term used by
+	// FindNext, FindPrev and ReplaceNext in the current window,
+	// adding it to the search history.
+	SetSearchText struct {
+		backend.BypassUndoCommand
+		Text string
+	}
+
+	// SetReplaceText Command sets the replacement used by
+	// ReplaceNext in the current window, adding it to the
+	// replace history.
+	SetReplaceText struct {
+		backend.BypassUndoCommand
+		Text string
+	}
+
+	// CycleSearchHistory Command makes an older, or when Forward
+	// is set a more recent, entry of the search history the
+	// current search term.
+	CycleSearchHistory struct {
+		backend.BypassUndoCommand
+		Forward bool
+	}
+
+	// CycleReplaceHistory Command makes an older, or when Forward
+	// is set a more recent, entry of the replace history the
+	// current replacement.
+	CycleReplaceHistory struct {
+		backend.BypassUndoCommand
+		Forward bool
+	}
+
+	// searchState holds the search and replace terms of a window.
+	searchState struct {
+		lock     sync.Mutex
+		searches searchHistory
+		replaces searchHistory
+		// The search term taken from the text under the cursors,
+		// which is searched for literally even in regex mode.
+		literal string
+		// Cancels the running find in files search, if any.
+		cancel context.CancelFunc
+	}
+
+	// searchHistory is a bounded list of terms, most recent first,
+	// together with the position of the current term in it.
+	searchHistory struct {
+		entries []string
+		pos     int
+	}
+)
+
+// Guards the creation of searchStates.
+var searchLock sync.Mutex
+
+// searchFor returns the search state of the window v belongs
+// to. Views without a window get an empty, throwaway state.
+func searchFor(v *backend.View) *searchState {
+	if w := v.Window(); w != nil {
+		return windowSearch(w)
+	}
+	return &searchState{}
+}
+
+func windowSearch(w *backend.Window) *searchState {
+	searchLock.Lock()
+	defer searchLock.Unlock()
+	if s, ok := w.Settings().Get(limeCmdSearch).(*searchState); ok {
+		return s
+	}
+	s := &searchState{}
+	w.Settings().Set(limeCmdSearch, s)
+	return s
+}
+
+func searchHistorySize(w *backend.Window) int {
+	if w == nil {
+		return 32
+	}
+	return w.Settings().Int("search_history_size", 32)
+}
+
+// add makes s the current term, moving it to the front of
+// the history if it's already there.
+func (h *searchHistory) add(s string, size int) {
+	h.pos = 0
+	for i, e := range h.entries {
+		if e == s {
+			h.entries = append(h.entries[:i], h.entries[i+1:]...)
+			break
+		}
+	}
+	h.entries = append([]string{s}, h.entries...)
+	if len(h.entries) > size {
+		h.entries = h.entries[:size]
+	}
+}
+
+// cycle moves the current term one entry back in the history,
+// or forward towards the most recent one.
+func (h *searchHistory) cycle(forward bool) {
+	if forward && h.pos > 0 {
+		h.pos--
+	} else if !forward && h.pos < len(h.entries)-1 {
+		h.pos++
+	}
+}
+
+func (h *searchHistory) current() string {
+	if len(h.entries) == 0 {
+		return ""
+	}
+	return h.entries[h.pos]
+}
+
+func (s *searchState) setSearch(text string, size int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.searches.add(text, size)
+	s.literal = ""
+}
+
+// setLiteralSearch is like setSearch, but text is searched
+// for literally even when searches use regular expressions.
+func (s *searchState) setLiteralSearch(text string, size int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.searches.add(text, size)
+	s.literal = text
+}
+
+func (s *searchState) setReplace(text string, size int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.replaces.add(text, size)
+}
+
+func (s *searchState) searchText() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.searches.current()
+}
+
+// searchTerm returns the search term and whether
+// it's to be searched for literally.
+func (s *searchState) searchTerm() (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.searches.current()
+	return t, t != "" && t == s.literal
+}
+
+func (s *searchState) replaceText() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.replaces.current()
+}
+
+// startFindInFiles cancels the running find in files search,
+// if any, and returns the context of a new one.
+func (s *searchState) startFindInFiles() (context.Context, context.CancelFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	return ctx, cancel
+}
+
+// setSearch makes text the search term of v's window.
+func setSearch(v *backend.View, text string) {
+	searchFor(v).setSearch(text, searchHistorySize(v.Window()))
+}
+
+// setReplace makes text the replacement of v's window.
+func setReplace(v *backend.View, text string) {
+	searchFor(v).setReplace(text, searchHistorySize(v.Window()))
+}
+
+// Run executes the SetSearchText command.
+func (c *SetSearchText) Run(w *backend.Window) error {
+	windowSearch(w).setSearch(c.Text, searchHistorySize(w))
+	return nil
+}
+
+// Run executes the SetReplaceText command.
+func (c *SetReplaceText) Run(w *backend.Window) error {
+	windowSearch(w).setReplace(c.Text, searchHistorySize(w))
+	return nil
+}
+
+// Run executes the CycleSearchHistory command.
+func (c *CycleSearchHistory) Run(w *backend.Window) error {
+	s := windowSearch(w)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.searches.cycle(c.Forward)
+	return nil
+}
+
+// Run executes the CycleReplaceHistory command.
+func (c *CycleReplaceHistory) Run(w *backend.Window) error {
+	s := windowSearch(w)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.replaces.cycle(c.Forward)
+	return nil
+}
+
+func init() {
+	register([]backend.Command{
+		&SetSearchText{},
+		&SetReplaceText{},
+		&CycleSearchHistory{},
+		&CycleReplaceHistory{},
+	})
+}
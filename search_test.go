@@ -0,0 +1,121 @@
+// Copyright 2016 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/limetext/backend"
+	"github.com/limetext/text"
+)
+
+// history returns a copy of the search, or replace,
+// history of s.
+func (s *searchState) history(replace bool) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	h := s.searches.entries
+	if replace {
+		h = s.replaces.entries
+	}
+	ret := make([]string, len(h))
+	copy(ret, h)
+	return ret
+}
+
+func TestSearchStatePerWindow(t *testing.T) {
+	ed := backend.GetEditor()
+	w1 := ed.NewWindow()
+	defer w1.Close()
+	w2 := ed.NewWindow()
+	defer w2.Close()
+
+	ed.CommandHandler().RunWindowCommand(w1, "set_search_text", backend.Args{"text": "foo"})
+	ed.CommandHandler().RunWindowCommand(w1, "set_replace_text", backend.Args{"text": "bar"})
+	ed.CommandHandler().RunWindowCommand(w2, "set_search_text", backend.Args{"text": "baz"})
+
+	if s := windowSearch(w1).searchText(); s != "foo" {
+		t.Errorf("Expected search text foo, but got %q", s)
+	}
+	if s := windowSearch(w1).replaceText(); s != "bar" {
+		t.Errorf("Expected replace text bar, but got %q", s)
+	}
+	if s := windowSearch(w2).searchText(); s != "baz" {
+		t.Errorf("Expected search text baz, but got %q", s)
+	}
+	if s := windowSearch(w2).replaceText(); s != "" {
+		t.Errorf("Expected empty replace text, but got %q", s)
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	w.Settings().Set("search_history_size", 3)
+
+	for _, s := range []string{"a", "b", "c", "b", "d"} {
+		ed.CommandHandler().RunWindowCommand(w, "set_search_text", backend.Args{"text": s})
+	}
+	if exp, h := []string{"d", "b", "c"}, windowSearch(w).history(false); !reflect.DeepEqual(h, exp) {
+		t.Errorf("Expected history %v, but got %v", exp, h)
+	}
+
+	tests := []struct {
+		forward bool
+		exp     string
+	}{
+		{false, "b"},
+		{false, "c"},
+		{false, "c"},
+		{true, "b"},
+		{true, "d"},
+		{true, "d"},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunWindowCommand(w, "cycle_search_history", backend.Args{"forward": test.forward})
+		if s := windowSearch(w).searchText(); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+
+	ed.CommandHandler().RunWindowCommand(w, "set_replace_text", backend.Args{"text": "x"})
+	ed.CommandHandler().RunWindowCommand(w, "set_replace_text", backend.Args{"text": ""})
+	ed.CommandHandler().RunWindowCommand(w, "cycle_replace_history", backend.Args{"forward": false})
+	if s := windowSearch(w).replaceText(); s != "x" {
+		t.Errorf("Expected replace text x, but got %q", s)
+	}
+}
+
+func TestFindNextUsesWindowSearch(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "foo bar foo bar")
+	v.EndEdit(e)
+	v.Settings().Set("find_wrap", false)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{0, 0})
+
+	ed.CommandHandler().RunWindowCommand(w, "set_search_text", backend.Args{"text": "bar"})
+	ed.CommandHandler().RunWindowCommand(w, "set_replace_text", backend.Args{"text": "baz"})
+
+	ed.CommandHandler().RunTextCommand(v, "find_next", nil)
+	if exp, sr := []text.Region{{4, 7}}, v.Sel().Regions(); !reflect.DeepEqual(sr, exp) {
+		t.Errorf("Expected %s, but got %s", exp, sr)
+	}
+	ed.CommandHandler().RunTextCommand(v, "replace_next", nil)
+	if exp, out := "foo bar foo baz", v.Substr(text.Region{0, v.Size()}); out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+}
@@ -5,6 +5,12 @@
 package commands
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/limetext/backend"
 	"github.com/limetext/text"
 )
@@ -20,8 +26,48 @@ type (
 	SelectAll struct {
 		backend.DefaultCommand
 	}
+
+	// ExpandSelection command grows every selection region to
+	// the unit specified by To enclosing it. Running it again
+	// grows the regions further outward.
+	ExpandSelection struct {
+		backend.DefaultCommand
+		// The unit to expand to.
+		To ExpandToType
+	}
+
+	// ExpandToType Specifies the unit "expand_selection" expands to.
+	ExpandToType int
 )
 
+const (
+	// ExpandToWord expands to the surrounding words.
+	ExpandToWord ExpandToType = iota
+	// ExpandToLine expands to full lines, and then
+	// adds the next line.
+	ExpandToLine
+	// ExpandToParagraph expands to the surrounding block of
+	// non-blank lines, and then adds the next block.
+	ExpandToParagraph
+	// ExpandToBrackets expands to the content of the enclosing
+	// brackets, and then to the brackets themselves.
+	ExpandToBrackets
+	// ExpandToIndentation expands to the surrounding lines
+	// indented at least as much, and then to the ones of
+	// the less indented line above.
+	ExpandToIndentation
+	// ExpandToScope expands to the extent of the innermost
+	// syntax scope, and then to the scopes around it.
+	ExpandToScope
+	// ExpandToTag expands to the content of the enclosing
+	// XML or HTML element, and then to its tags.
+	ExpandToTag
+)
+
+// Matches the XML and HTML tags, the first group is set for closing
+// tags, the second is the name and the third set for self closing tags.
+var tagRe = regexp.MustCompile(`<(/?)([A-Za-z][\w:.-]*)(?:\s[^<>]*?)?(/?)>`)
+
 // Run executes the SingleSelection command.
 func (c *SingleSelection) Run(v *backend.View, e *backend.Edit) error {
 	/*
@@ -48,9 +94,321 @@ func (c *SelectAll) Run(v *backend.View, e *backend.Edit) error {
 	return nil
 }
 
+// Set will define the unit to expand to.
+func (t *ExpandToType) Set(v interface{}) error {
+	switch to := v.(type) {
+	case ExpandToType:
+		*t = to
+	case string:
+		switch to {
+		case "word":
+			*t = ExpandToWord
+		case "line":
+			*t = ExpandToLine
+		case "paragraph":
+			*t = ExpandToParagraph
+		case "brackets":
+			*t = ExpandToBrackets
+		case "indentation":
+			*t = ExpandToIndentation
+		case "scope":
+			*t = ExpandToScope
+		case "tag":
+			*t = ExpandToTag
+		default:
+			return fmt.Errorf("expand_selection: Unimplemented 'to' type: %s", to)
+		}
+	default:
+		return fmt.Errorf("expand_selection: Unexpected 'to' value: %v", v)
+	}
+	return nil
+}
+
+// Run executes the ExpandSelection command.
+func (c *ExpandSelection) Run(v *backend.View, e *backend.Edit) error {
+	var expand func(r text.Region) text.Region
+	switch c.To {
+	case ExpandToWord:
+		expand = func(r text.Region) text.Region { return expandToWord(v, r) }
+	case ExpandToLine:
+		expand = func(r text.Region) text.Region { return expandToLine(v, r) }
+	case ExpandToParagraph:
+		expand = func(r text.Region) text.Region { return expandToParagraph(v, r) }
+	case ExpandToBrackets:
+		bs := newBracketScanner(v)
+		expand = func(r text.Region) text.Region { return expandToBrackets(bs, r) }
+	case ExpandToIndentation:
+		expand = func(r text.Region) text.Region { return expandToIndentation(v, r) }
+	case ExpandToScope:
+		expand = func(r text.Region) text.Region { return expandToScope(v, r) }
+	case ExpandToTag:
+		expand = func(r text.Region) text.Region { return expandToTag(v, r) }
+	default:
+		return fmt.Errorf("expand_selection: Unimplemented 'to' action: %d", c.To)
+	}
+	sel := v.Sel()
+	rs := sel.Regions()
+	for i, r := range rs {
+		rs[i] = expand(text.Region{r.Begin(), r.End()})
+	}
+	sel.Clear()
+	sel.AddAll(rs)
+	return nil
+}
+
+func expandToWord(v *backend.View, r text.Region) text.Region {
+	seps := v.Settings().String("word_separators", backend.DEFAULT_SEPARATORS)
+	const (
+		word = iota
+		space
+		separator
+	)
+	class := func(p int) int {
+		c := v.SubstrR(text.Region{p, p + 1})[0]
+		switch {
+		case unicode.IsSpace(c):
+			return space
+		case strings.ContainsRune(seps, c):
+			return separator
+		}
+		return word
+	}
+	w := r
+	for w.A > 0 && class(w.A-1) == word {
+		w.A--
+	}
+	for w.B < v.Size() && class(w.B) == word {
+		w.B++
+	}
+	if w != r || r.Empty() {
+		return w
+	}
+	// Already whole words, so add the run of word, white space or
+	// separator characters after them, or before them at the end.
+	if r.B < v.Size() {
+		for c := class(r.B); r.B < v.Size() && class(r.B) == c; {
+			r.B++
+		}
+	} else if r.A > 0 {
+		for c := class(r.A - 1); r.A > 0 && class(r.A-1) == c; {
+			r.A--
+		}
+	}
+	return r
+}
+
+func expandToLine(v *backend.View, r text.Region) text.Region {
+	end := r.B
+	if !r.Empty() {
+		end--
+	}
+	l := text.Region{v.FullLine(r.A).Begin(), v.FullLine(end).End()}
+	if l == r && r.B < v.Size() {
+		l.B = v.FullLine(r.B).End()
+	}
+	return l
+}
+
+// isBlankLine reports whether the line at row is empty
+// or only has white space.
+func isBlankLine(v *backend.View, row int) bool {
+	return strings.TrimSpace(v.Substr(v.Line(v.TextPoint(row, 0)))) == ""
+}
+
+func lastRow(v *backend.View) int {
+	row, _ := v.RowCol(v.Size())
+	return row
+}
+
+func expandToParagraph(v *backend.View, r text.Region) text.Region {
+	first, _ := v.RowCol(r.A)
+	last, _ := v.RowCol(r.B)
+	if !r.Empty() && last > first && v.Line(r.B).Begin() == r.B {
+		last--
+	}
+	for first > 0 && !isBlankLine(v, first-1) {
+		first--
+	}
+	end := lastRow(v)
+	for last < end && !isBlankLine(v, last+1) {
+		last++
+	}
+	p := text.Region{v.TextPoint(first, 0), v.FullLine(v.TextPoint(last, 0)).End()}
+	if p.Covers(r) && p != r || last >= end {
+		return p
+	}
+	// Add the blank lines and the paragraph after.
+	for last < end && isBlankLine(v, last+1) {
+		last++
+	}
+	for last < end && !isBlankLine(v, last+1) {
+		last++
+	}
+	p.B = v.FullLine(v.TextPoint(last, 0)).End()
+	return p
+}
+
+func expandToBrackets(bs *bracketScanner, r text.Region) text.Region {
+	for p := r.A; ; {
+		o := bs.enclosing(p)
+		if o == -1 {
+			return r
+		}
+		c := bs.findClose(o)
+		if c == -1 {
+			return r
+		}
+		if c >= r.B {
+			if content := (text.Region{o + 1, c}); content != r {
+				return content
+			}
+			return text.Region{o, c + 1}
+		}
+		p = o
+	}
+}
+
+// indentation returns the width of the indentation of the
+// line at row, or -1 if it's blank.
+func indentation(v *backend.View, row int) int {
+	if isBlankLine(v, row) {
+		return -1
+	}
+	w, _ := leadingWidth(v.Substr(v.Line(v.TextPoint(row, 0))), v.Settings().Int("tab_size", 4))
+	return w
+}
+
+// indentBlock returns the lines around row indented by at least
+// level, including the blank lines between them.
+func indentBlock(v *backend.View, row, level int) text.Region {
+	inBlock := func(row int) bool {
+		return isBlankLine(v, row) || indentation(v, row) >= level
+	}
+	first, last, end := row, row, lastRow(v)
+	for first > 0 && inBlock(first-1) {
+		first--
+	}
+	for last < end && inBlock(last+1) {
+		last++
+	}
+	for first < row && isBlankLine(v, first) {
+		first++
+	}
+	for last > row && isBlankLine(v, last) {
+		last--
+	}
+	return text.Region{v.TextPoint(first, 0), v.Line(v.TextPoint(last, 0)).End()}
+}
+
+func expandToIndentation(v *backend.View, r text.Region) text.Region {
+	row, _ := v.RowCol(r.A)
+	for end := lastRow(v); row < end && isBlankLine(v, row); row++ {
+	}
+	level := indentation(v, row)
+	if level == -1 {
+		return r
+	}
+	for {
+		b := indentBlock(v, row, level)
+		if b.Covers(r) && b != r {
+			return b
+		}
+		// Go up to the less indented line above the block.
+		row, _ = v.RowCol(b.A)
+		for row--; row >= 0 && isBlankLine(v, row); row-- {
+		}
+		if row < 0 {
+			return r
+		}
+		level = indentation(v, row)
+	}
+}
+
+// scopeExtent returns the region around pos where the
+// scope names start with names.
+func scopeExtent(v *backend.View, pos int, names []string) text.Region {
+	in := func(p int) bool {
+		ns := strings.Fields(v.ScopeName(p))
+		if len(ns) < len(names) {
+			return false
+		}
+		for i, n := range names {
+			if ns[i] != n {
+				return false
+			}
+		}
+		return true
+	}
+	a, b := pos, pos
+	for a > 0 && in(a-1) {
+		a--
+	}
+	for b < v.Size() && in(b) {
+		b++
+	}
+	return text.Region{a, b}
+}
+
+func expandToScope(v *backend.View, r text.Region) text.Region {
+	names := strings.Fields(v.ScopeName(r.A))
+	for i := len(names); i > 0; i-- {
+		if s := scopeExtent(v, r.A, names[:i]); s.Covers(r) && s != r {
+			return s
+		}
+	}
+	return r
+}
+
+func expandToTag(v *backend.View, r text.Region) text.Region {
+	type tag struct {
+		name   string
+		region text.Region
+	}
+	var (
+		stack []tag
+		src   = v.Substr(text.Region{0, v.Size()})
+		ret   = r
+		// The byte offset and rune position of the last match.
+		off, pos int
+	)
+	// grow makes c the result if it's the smallest
+	// region so far which is larger than r.
+	grow := func(c text.Region) {
+		if c.Covers(r) && c != r && (ret == r || ret.Covers(c)) {
+			ret = c
+		}
+	}
+	for _, m := range tagRe.FindAllStringSubmatchIndex(src, -1) {
+		a := pos + utf8.RuneCountInString(src[off:m[0]])
+		b := a + utf8.RuneCountInString(src[m[0]:m[1]])
+		off, pos = m[1], b
+		name := src[m[4]:m[5]]
+		switch {
+		case m[7] > m[6]:
+			// Self closing.
+			grow(text.Region{a, b})
+		case m[3] == m[2]:
+			stack = append(stack, tag{name, text.Region{a, b}})
+		default:
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name != name {
+					continue
+				}
+				open := stack[i].region
+				stack = stack[:i]
+				grow(text.Region{open.B, a})
+				grow(text.Region{open.A, b})
+				break
+			}
+		}
+	}
+	return ret
+}
+
 func init() {
 	register([]backend.Command{
 		&SingleSelection{},
 		&SelectAll{},
+		&ExpandSelection{},
 	})
 }
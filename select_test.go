@@ -7,6 +7,7 @@ package commands
 import (
 	"testing"
 
+	"github.com/limetext/backend"
 	"github.com/limetext/text"
 )
 
@@ -59,3 +60,61 @@ func TestSelectAll(t *testing.T) {
 
 	runFindTest(tests, t, "select_all")
 }
+
+func TestExpandSelection(t *testing.T) {
+	ed := backend.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	const (
+		lines    = "foo bar_baz(x)\nline two\n\npara three\nend\n"
+		brackets = "f(a, [b, c])"
+		indented = "a\n  b\n  c\n    d\n  e\nf\n"
+		tags     = "<a><b>x</b><br/></a>"
+	)
+	tests := []struct {
+		text string
+		to   string
+		in   text.Region
+		// The selection after each run.
+		exp []text.Region
+	}{
+		{lines, "word", text.Region{5, 5}, []text.Region{{4, 11}, {4, 12}, {4, 13}, {4, 14}, {4, 15}}},
+		{"foo  bar", "word", text.Region{6, 6}, []text.Region{{5, 8}, {3, 8}, {0, 8}, {0, 8}}},
+		{lines, "word", text.Region{6, 12}, []text.Region{{4, 13}}},
+		{lines, "line", text.Region{16, 16}, []text.Region{{15, 24}, {15, 25}}},
+		{lines, "paragraph", text.Region{16, 16}, []text.Region{{0, 24}, {0, 40}, {0, 40}}},
+		{brackets, "brackets", text.Region{6, 6}, []text.Region{{6, 10}, {5, 11}, {2, 11}, {1, 12}, {1, 12}}},
+		{indented, "indentation", text.Region{13, 13}, []text.Region{{10, 15}, {2, 19}, {0, 21}}},
+		{tags, "tag", text.Region{6, 6}, []text.Region{{6, 7}, {3, 11}, {3, 16}, {0, 20}, {0, 20}}},
+	}
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, test.text)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().Add(test.in)
+		for j, exp := range test.exp {
+			ed.CommandHandler().RunTextCommand(v, "expand_selection", backend.Args{"to": test.to})
+			if sr := v.Sel().Regions(); len(sr) != 1 || sr[0] != exp {
+				t.Errorf("Test %d, run %d: Expected %v, but got %v", i, j, exp, sr)
+			}
+		}
+	}
+
+	setTestSyntax(t, v, "x \"ab\" y\n")
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{3, 3})
+	for i, exp := range []text.Region{{2, 6}, {0, 9}} {
+		ed.CommandHandler().RunTextCommand(v, "expand_selection", backend.Args{"to": "scope"})
+		if sr := v.Sel().Regions(); len(sr) != 1 || sr[0] != exp {
+			t.Errorf("Scope run %d: Expected %v, but got %v", i, exp, sr)
+		}
+	}
+}